@@ -0,0 +1,118 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// RecvBatch receives up to len(buf) items from the elastic channel
+// and stores them in buf. It blocks until at least one item is
+// available, and then moves all available items (up to len(buf)) with
+// a single synchronization with the channel goroutine. Returns the
+// number of items received, or 0 if the channel is closed (or buf is
+// empty).
+//
+// Receiving with RecvBatch and from R concurrently is allowed, but
+// then items are split arbitrarily between the receivers.
+func (e Elastic[E]) RecvBatch(buf []E) int {
+	if len(buf) == 0 {
+		return 0
+	}
+	v, ok := <-e.R
+	if !ok {
+		return 0
+	}
+	buf[0] = v
+	n := 1
+	if n == len(buf) {
+		return n
+	}
+	en := e.eng
+	if en.do(func() { n += en.popBatch(buf[n:]) }) {
+		return n
+	}
+	// Goroutine has exited. Get what is left in the buffer.
+	for n < len(buf) {
+		if buf[n], ok = <-e.R; !ok {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// SendBatch sends the items in vs to the elastic channel, in order.
+// The items that fit are moved to the queue with a single
+// synchronization with the channel goroutine; any items that do not
+// fit (Block policy) are sent to S, blocking as required. Items
+// previously sent to S by the same goroutine are delivered before
+// the items in vs. The caller may reuse vs once SendBatch returns.
+// Like a send to S, SendBatch panics if S has been closed (and vs is
+// not empty).
+func (e Elastic[E]) SendBatch(vs []E) {
+	var n int
+	en := e.eng
+	en.do(func() { n = en.pushBatch(vs) })
+	for _, v := range vs[n:] {
+		e.S <- v
+	}
+}
+
+// popBatch moves up to len(buf) items from the receive-side buffer
+// and the queue (in this order) to buf. Returns the number of items
+// moved.
+func (en *engine[E]) popBatch(buf []E) int {
+	n := 0
+	for n < len(buf) {
+		select {
+		case buf[n] = <-en.cout:
+			n++
+			continue
+		default:
+		}
+		break
+	}
+	if en.tq != nil {
+		en.expire()
+	}
+	if en.st.Paused {
+		return n
+	}
+	for ; n < len(buf) && en.q.Len() > 0; n++ {
+		buf[n] = en.pop()
+		en.st.Out++
+	}
+	return n
+}
+
+// pushBatch pushes the items in the send-side buffer, and then the
+// items in vs, to the queue, unless the queue becomes full (Block
+// policy). Returns the number of items pushed from vs.
+func (en *engine[E]) pushBatch(vs []E) int {
+	for i := len(en.cin); i > 0; i-- {
+		if en.blocking() {
+			return 0
+		}
+		en.push(<-en.cin)
+	}
+	if en.blocking() {
+		return 0
+	}
+	// If S is closed, leave vs to SendBatch, whose send to S then
+	// panics, as it should.
+	select {
+	case v, ok := <-en.cin:
+		if !ok {
+			return 0
+		}
+		en.push(v)
+	default:
+	}
+	for i, v := range vs {
+		if en.blocking() {
+			return i
+		}
+		en.push(v)
+	}
+	return len(vs)
+}
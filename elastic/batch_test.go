@@ -0,0 +1,91 @@
+package elastic
+
+import "testing"
+
+const batchSz = 1024
+
+func produceBatch(n int, elc ElasticT, end chan<- int) {
+	var i int
+	buf := make([]T, 0, batchSz)
+	for i = 0; i < n; i++ {
+		buf = append(buf, T(i))
+		if len(buf) == batchSz {
+			elc.SendBatch(buf)
+			buf = buf[:0]
+		}
+	}
+	elc.SendBatch(buf)
+	close(elc.S)
+	end <- i
+}
+
+func consumeBatch(n int, elc ElasticT, end chan<- int) {
+	var i int
+	buf := make([]T, batchSz)
+	for i < n {
+		m := elc.RecvBatch(buf)
+		if m == 0 {
+			break
+		}
+		for _, v := range buf[:m] {
+			if v != T(i) {
+				end <- i
+				return
+			}
+			i++
+		}
+	}
+	end <- i
+}
+
+func TestBatch(t *testing.T) {
+	const N = 100000
+	elc := NewElasticT()
+	endP := make(chan int)
+	endC := make(chan int)
+	go produceBatch(N, elc, endP)
+	go consumeBatch(N, elc, endC)
+	if r := <-endP; r != N {
+		t.Fatalf("Producer ret %d != %d", r, N)
+	}
+	if r := <-endC; r != N {
+		t.Fatalf("Consumer ret %d != %d", r, N)
+	}
+	if n := elc.RecvBatch(make([]T, 1)); n != 0 {
+		t.Fatalf("RecvBatch after close: %d", n)
+	}
+}
+
+func TestBatchMixed(t *testing.T) {
+	const N = 1000
+	elc := NewBounded[T](NoShrink, 100, Block, nil)
+	end := make(chan int)
+	go consume(3*N, elc.R, end)
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	vs := make([]T, N)
+	for i := range vs {
+		vs[i] = T(N + i)
+	}
+	elc.SendBatch(vs)
+	for i := 2 * N; i < 3*N; i++ {
+		elc.S <- T(i)
+	}
+	close(elc.S)
+	if r := <-end; r != 3*N {
+		t.Fatalf("Consumer ret %d != %d", r, 3*N)
+	}
+}
+
+func TestSendBatchClosed(t *testing.T) {
+	elc := NewElasticT()
+	elc.SendBatch([]T{1, 2})
+	close(elc.S)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("SendBatch on closed channel did not panic")
+		}
+	}()
+	elc.SendBatch([]T{3})
+}
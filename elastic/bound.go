@@ -0,0 +1,55 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// Overflow type encodes values that specify what a bounded elastic
+// channel does when its queue is full and a new item arrives.
+type Overflow int
+
+const (
+	// Overflow values.
+	Block      Overflow = iota // Stop receiving (block the sender).
+	DropNewest                 // Drop the arriving item.
+	DropOldest                 // Drop the item at the queue front.
+)
+
+// Drops holds the overflow counters of an elastic channel.
+type Drops struct {
+	Newest  uint64 // Items dropped by DropNewest.
+	Oldest  uint64 // Items dropped by DropOldest.
+	Blocked uint64 // Times the sender was blocked (Block, group).
+	Failed  uint64 // Items lost due to spill I/O or codec errors.
+	Expired uint64 // Items dropped because their TTL expired.
+	Group   uint64 // Items dropped due to the group budget.
+}
+
+// NewBounded creates and returns a new elastic channel whose internal
+// queue holds at most max items. When the queue is full, the channel
+// behaves as specified by policy. If onDrop is not nil, it is called
+// with every item dropped (DropNewest, DropOldest). It is called from
+// the elastic channel goroutine and must not block; to forward
+// dropped items to a dead-letter channel, do a non-blocking send.
+//
+// Notice that, in addition to the items in the queue, up to
+// sendBuffer and receiveBuffer items may be held in the fixed buffers
+// at the send and receive sides of the channel. Use
+// NewElasticWithOptions to change the buffer sizes.
+func NewBounded[E any](mode ShrinkMode,
+	max int, policy Overflow, onDrop func(E)) Elastic[E] {
+
+	o := DefaultOptions[E]()
+	o.Mode = mode
+	o.MaxQueue = max
+	o.Overflow = policy
+	o.OnDrop = onDrop
+	return mustElastic(o)
+}
+
+// Drops returns the overflow counters of the elastic channel. It is
+// safe to call while the channel is in use.
+func (e Elastic[E]) Drops() Drops {
+	return e.Stats().Drops
+}
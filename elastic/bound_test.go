@@ -0,0 +1,83 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+// recvAll receives from c until it is closed, checks that the
+// received values are increasing, and returns them.
+func recvAll(t *testing.T, c <-chan T) []T {
+	t.Helper()
+	var r []T
+	for v := range c {
+		if len(r) > 0 && v <= r[len(r)-1] {
+			t.Fatalf("Got %d after %d", v, r[len(r)-1])
+		}
+		r = append(r, v)
+	}
+	return r
+}
+
+func TestBoundDropNewest(t *testing.T) {
+	const N, M = 1000, 100
+	var nd int
+	elc := NewBounded(NoShrink, M, DropNewest, func(T) { nd++ })
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	close(elc.S)
+	r := recvAll(t, elc.R)
+	d := elc.Drops()
+	if len(r)+int(d.Newest) != N || d.Oldest != 0 || nd != int(d.Newest) {
+		t.Fatalf("Received %d, drops %+v, onDrop %d", len(r), d, nd)
+	}
+	for i := 0; i < M; i++ {
+		if r[i] != T(i) {
+			t.Fatalf("Got %d != %d", r[i], i)
+		}
+	}
+}
+
+func TestBoundDropOldest(t *testing.T) {
+	const N, M = 1000, 100
+	elc := NewBounded[T](Shrink, M, DropOldest, nil)
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	close(elc.S)
+	r := recvAll(t, elc.R)
+	d := elc.Drops()
+	if len(r)+int(d.Oldest) != N || d.Newest != 0 {
+		t.Fatalf("Received %d, drops %+v", len(r), d)
+	}
+	for i := 0; i < M; i++ {
+		if r[len(r)-1-i] != T(N-1-i) {
+			t.Fatalf("Got %d != %d", r[len(r)-1-i], N-1-i)
+		}
+	}
+}
+
+func TestBoundBlock(t *testing.T) {
+	const N, M = 1000, 100
+	elc := NewBounded[T](Shrink, M, Block, nil)
+	sent := make(chan int)
+	go func() {
+		var i int
+		for i = 0; i < N; i++ {
+			select {
+			case elc.S <- T(i):
+				continue
+			case <-time.After(10 * time.Millisecond):
+			}
+			break
+		}
+		sent <- i
+	}()
+	if n := <-sent; n != M+sendBuffer+receiveBuffer {
+		t.Fatalf("Sent %d items before blocking", n)
+	}
+	if d := elc.Drops(); d.Blocked == 0 || d.Newest != 0 || d.Oldest != 0 {
+		t.Fatalf("Drops = %+v", d)
+	}
+}
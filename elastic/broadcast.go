@@ -0,0 +1,135 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import "errors"
+
+// Broadcast is an elastic channel with one send side (S) and many
+// receive sides (subscribers). Every subscriber receives every item
+// sent after it has subscribed. Each subscriber has its own elastic
+// queue, so a slow subscriber never blocks the sender or the other
+// subscribers. Subscribers can join and leave at any time.
+//
+// Closing S closes all subscribers, once they have received all the
+// items sent.
+type Broadcast[E any] struct {
+	S    chan<- E // Send direction.
+	ctl  chan func(subs *[]*Subscriber[E])
+	done chan struct{}
+}
+
+// Subscriber is a receive side of a Broadcast channel.
+type Subscriber[E any] struct {
+	R <-chan E // Receive direction.
+	e Elastic[E]
+	b Broadcast[E]
+}
+
+// ErrSubscriber is returned by Subscribe if the subscriber options
+// would let the subscriber block the Broadcast channel.
+var ErrSubscriber = errors.New("elastic: invalid subscriber options")
+
+// NewBroadcast creates and returns a new broadcast elastic channel,
+// with no subscribers.
+func NewBroadcast[E any]() Broadcast[E] {
+	cin := make(chan E, sendBuffer)
+	b := Broadcast[E]{
+		S:    cin,
+		ctl:  make(chan func(subs *[]*Subscriber[E])),
+		done: make(chan struct{}),
+	}
+	go b.run(cin)
+	return b
+}
+
+// Subscribe adds a new subscriber to the broadcast channel, with its
+// own elastic queue, created with options o (start from
+// DefaultOptions). The queue may be bounded (MaxQueue, MaxBytes),
+// but then its overflow policy cannot be Block, and it cannot join a
+// group with the BlockLargest policy. Also, o.Context must be nil;
+// use Subscriber.Leave instead. If the broadcast channel has been
+// closed, the subscriber's R is closed.
+func (b Broadcast[E]) Subscribe(o Options[E]) (*Subscriber[E], error) {
+	if o.Overflow == Block && (o.MaxQueue < maxQSz || o.MaxBytes > 0) ||
+		o.Group != nil && o.Group.policy == BlockLargest ||
+		o.Context != nil {
+		return nil, ErrSubscriber
+	}
+	e, err := NewElasticWithOptions(o)
+	if err != nil {
+		return nil, err
+	}
+	s := &Subscriber[E]{R: e.R, e: e, b: b}
+	if !b.do(func(subs *[]*Subscriber[E]) { *subs = append(*subs, s) }) {
+		close(e.S)
+	}
+	return s, nil
+}
+
+// Leave removes the subscriber from the broadcast channel. Items not
+// yet received are discarded, and R is closed.
+func (s *Subscriber[E]) Leave() {
+	s.b.do(func(subs *[]*Subscriber[E]) {
+		for i, s1 := range *subs {
+			if s1 == s {
+				*subs = append((*subs)[:i], (*subs)[i+1:]...)
+				break
+			}
+		}
+	})
+	s.e.Cancel()
+}
+
+// Stats returns a snapshot of the state and the counters of the
+// subscriber's elastic queue.
+func (s *Subscriber[E]) Stats() Stats {
+	return s.e.Stats()
+}
+
+// do runs f from the broadcast channel goroutine, with the list of
+// subscribers, and waits for it to complete. Returns false, without
+// running f, if the goroutine has exited.
+func (b Broadcast[E]) do(f func(subs *[]*Subscriber[E])) bool {
+	ack := make(chan struct{})
+	select {
+	case b.ctl <- func(subs *[]*Subscriber[E]) { f(subs); close(ack) }:
+		<-ack
+		return true
+	case <-b.done:
+		return false
+	}
+}
+
+// run runs as the broadcast channel goroutine. It sends every item
+// received from cin to the send side of every subscriber.
+func (b Broadcast[E]) run(cin <-chan E) {
+	var subs []*Subscriber[E]
+	defer close(b.done)
+	for {
+		select {
+		case v, ok := <-cin:
+			if !ok {
+				for _, s := range subs {
+					close(s.e.S)
+				}
+				return
+			}
+			for _, s := range subs {
+				s.e.S <- v
+			}
+		case f := <-b.ctl:
+			// Items already sent go to the current
+			// subscribers only.
+			for i := len(cin); i > 0; i-- {
+				v := <-cin
+				for _, s := range subs {
+					s.e.S <- v
+				}
+			}
+			f(&subs)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package elastic
+
+import "testing"
+
+func TestBroadcast(t *testing.T) {
+	const N = 1000
+	b := NewBroadcast[T]()
+	s1, err := b.Subscribe(DefaultOptions[T]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.MaxQueue, o.Overflow = 10, DropOldest
+	s2, err := b.Subscribe(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3, _ := b.Subscribe(DefaultOptions[T]())
+	// Nobody receives; the sender must not block.
+	for i := 0; i < N; i++ {
+		b.S <- T(i)
+	}
+	s3.Leave()
+	if _, ok := <-s3.R; ok {
+		t.Fatal("R not closed after Leave")
+	}
+	s4, _ := b.Subscribe(DefaultOptions[T]())
+	b.S <- N
+	close(b.S)
+	if r := recvAll(t, s1.R); len(r) != N+1 {
+		t.Fatalf("s1 received %d", len(r))
+	}
+	r := recvAll(t, s2.R)
+	if len(r) != 10 || r[9] != N {
+		t.Fatalf("s2 received %v", r)
+	}
+	if s := s2.Stats(); s.Drops.Oldest != N+1-10 {
+		t.Fatalf("s2 stats: %+v", s)
+	}
+	if r := recvAll(t, s4.R); len(r) != 1 || r[0] != N {
+		t.Fatalf("s4 received %v", r)
+	}
+	s5, _ := b.Subscribe(DefaultOptions[T]())
+	if _, ok := <-s5.R; ok {
+		t.Fatal("Subscribed after close")
+	}
+}
+
+func TestBroadcastCheck(t *testing.T) {
+	b := NewBroadcast[T]()
+	o := DefaultOptions[T]()
+	o.MaxQueue = 10
+	if _, err := b.Subscribe(o); err != ErrSubscriber {
+		t.Fatalf("err = %v", err)
+	}
+	o = DefaultOptions[T]()
+	o.Sizer = func(T) int { return 8 }
+	o.MaxBytes = 100
+	if _, err := b.Subscribe(o); err != ErrSubscriber {
+		t.Fatalf("MaxBytes: err = %v", err)
+	}
+	o.Overflow = DropOldest
+	if _, err := b.Subscribe(o); err != nil {
+		t.Fatalf("MaxBytes, DropOldest: err = %v", err)
+	}
+	o = DefaultOptions[T]()
+	o.Group = NewElasticGroup(100, false, BlockLargest)
+	if _, err := b.Subscribe(o); err != ErrSubscriber {
+		t.Fatalf("BlockLargest group: err = %v", err)
+	}
+	o.Group = NewElasticGroup(100, false, DropLargest)
+	if _, err := b.Subscribe(o); err != nil {
+		t.Fatalf("DropLargest group: err = %v", err)
+	}
+	close(b.S)
+}
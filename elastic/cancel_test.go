@@ -0,0 +1,59 @@
+package elastic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCancel(t *testing.T) {
+	const N = 1000
+	elc := NewElasticT()
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	for i := 0; i < 10; i++ {
+		<-elc.R
+	}
+	vs := elc.Cancel()
+	if len(vs) != N-10 {
+		t.Fatalf("Cancel returned %d items", len(vs))
+	}
+	for i, v := range vs {
+		if v != T(i+10) {
+			t.Fatalf("Got %d != %d", v, i+10)
+		}
+	}
+	if _, ok := <-elc.R; ok {
+		t.Fatal("R not closed")
+	}
+	if vs := elc.Cancel(); vs != nil {
+		t.Fatal("Second Cancel returned items")
+	}
+	if s := elc.Stats(); s.Len != 0 {
+		t.Fatalf("Stats: %+v", s)
+	}
+}
+
+func TestCancelContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	o := DefaultOptions[T]()
+	o.Context = ctx
+	elc, _ := NewElasticWithOptions(o)
+	for i := 0; i < 1000; i++ {
+		elc.S <- T(i)
+	}
+	cancel()
+	select {
+	case <-elc.eng.done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Goroutine did not exit")
+	}
+	n := 0
+	for range elc.R {
+		n++
+	}
+	if n != 0 {
+		t.Fatalf("Received %d items after cancel", n)
+	}
+}
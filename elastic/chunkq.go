@@ -0,0 +1,167 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// chunk is a fixed-size block of queue elements.
+type chunk[E any] struct {
+	b    []E
+	next *chunk[E]
+}
+
+// chunkQ is a queue stored in a linked list of fixed-size chunks.
+//
+// It grows and shrinks one chunk at a time, so elements are never
+// copied, and push / pop are O(1). A single drained chunk is kept as
+// a spare, to avoid reallocating when the queue length oscillates
+// around a chunk boundary. Compact releases the spare only after it
+// has gone unused for a while (see Compact).
+//
+// Queue operations are *NOT* thread safe.
+type chunkQ[E any] struct {
+	csz   int       // Chunk size (elements).
+	head  *chunk[E] // First chunk (front element).
+	tail  *chunk[E] // Last chunk (back element).
+	hi    int       // Index of front element in head.
+	ti    int       // Index after the back element in tail.
+	n     int       // Number of elements.
+	nc    int       // Number of chunks in the list.
+	spare *chunk[E] // Drained chunk kept for reuse, or nil.
+	idle  int       // Compact calls since the spare was last used.
+}
+
+// newChunkQ creates and returns a new, empty, chunked queue with
+// chunks of csz elements.
+func newChunkQ[E any](csz int) *chunkQ[E] {
+	if csz <= 0 {
+		panic("Invalid chunk size")
+	}
+	c := &chunk[E]{b: make([]E, csz)}
+	return &chunkQ[E]{csz: csz, head: c, tail: c, nc: 1}
+}
+
+// Len returns the number of elements waiting in the queue.
+func (cq *chunkQ[E]) Len() int {
+	return cq.n
+}
+
+// Cap returns the capacity of the queue (# of element slots currently
+// allocated, including the spare chunk).
+func (cq *chunkQ[E]) Cap() int {
+	if cq.spare != nil {
+		return (cq.nc + 1) * cq.csz
+	}
+	return cq.nc * cq.csz
+}
+
+// newChunk returns the spare chunk, or a newly allocated one.
+func (cq *chunkQ[E]) newChunk() *chunk[E] {
+	if c := cq.spare; c != nil {
+		cq.spare, cq.idle = nil, 0
+		return c
+	}
+	return &chunk[E]{b: make([]E, cq.csz)}
+}
+
+// freeChunk keeps c as the spare chunk, unless there is one already.
+func (cq *chunkQ[E]) freeChunk(c *chunk[E]) {
+	if cq.spare == nil {
+		c.next = nil
+		cq.spare, cq.idle = c, 0
+	}
+}
+
+// PeekFront returns the front (head) element of the queue, without
+// removing it. Returns ok == false if the queue is empty.
+func (cq *chunkQ[E]) PeekFront() (el E, ok bool) {
+	if cq.n == 0 {
+		return el, false
+	}
+	return cq.head.b[cq.hi], true
+}
+
+// PopFront removes the front (head) element from the queue and
+// returns it. Returns ok == false if the queue was empty.
+func (cq *chunkQ[E]) PopFront() (el E, ok bool) {
+	var zero E
+	if cq.n == 0 {
+		return zero, false
+	}
+	el = cq.head.b[cq.hi]
+	cq.head.b[cq.hi] = zero
+	cq.hi++
+	cq.n--
+	if cq.n == 0 {
+		// Reuse the current chunk from its start.
+		for c := cq.head.next; c != nil; {
+			nx := c.next
+			cq.freeChunk(c)
+			cq.nc--
+			c = nx
+		}
+		cq.head.next = nil
+		cq.tail = cq.head
+		cq.hi, cq.ti = 0, 0
+	} else if cq.hi == cq.csz {
+		c := cq.head
+		cq.head = c.next
+		cq.freeChunk(c)
+		cq.nc--
+		cq.hi = 0
+	}
+	return el, true
+}
+
+// PushBack adds element el to the back (tail) of the queue. It always
+// succeeds (returns ok == true).
+func (cq *chunkQ[E]) PushBack(el E) (ok bool) {
+	if cq.ti == cq.csz {
+		c := cq.newChunk()
+		cq.tail.next = c
+		cq.tail = c
+		cq.nc++
+		cq.ti = 0
+	}
+	cq.tail.b[cq.ti] = el
+	cq.ti++
+	cq.n++
+	return true
+}
+
+// PushFront adds element el to the front (head) of the queue. It
+// always succeeds (returns ok == true).
+func (cq *chunkQ[E]) PushFront(el E) (ok bool) {
+	if cq.n == 0 {
+		cq.hi, cq.ti = 0, 0
+		return cq.PushBack(el)
+	}
+	if cq.hi == 0 {
+		c := cq.newChunk()
+		c.next = cq.head
+		cq.head = c
+		cq.nc++
+		cq.hi = cq.csz
+	}
+	cq.hi--
+	cq.head.b[cq.hi] = el
+	cq.n++
+	return true
+}
+
+// Compact releases the spare chunk. Since drained chunks are released
+// as the queue shrinks, this is the only memory a chunked queue can
+// give back. With a shrink mode that compacts on every pop, releasing
+// the spare right away would free and reallocate a chunk whenever the
+// queue length oscillates around a chunk boundary; so, unless the
+// queue is empty, the spare is released only after csz calls to
+// Compact in which it has not been used. Argument sz is ignored.
+func (cq *chunkQ[E]) Compact(sz int) {
+	if cq.spare == nil {
+		return
+	}
+	if cq.idle++; cq.n == 0 || cq.idle >= cq.csz {
+		cq.spare, cq.idle = nil, 0
+	}
+}
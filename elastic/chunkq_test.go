@@ -0,0 +1,154 @@
+package elastic
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestChunkQ(t *testing.T) {
+	q := newChunkQ[int](4)
+	var m []int
+	r := rand.New(rand.NewSource(1))
+	for k := 0; k < 20000; k++ {
+		switch x := r.Intn(10); {
+		case x < 4:
+			q.PushBack(k)
+			m = append(m, k)
+		case x < 5:
+			q.PushFront(k)
+			m = append([]int{k}, m...)
+		case x < 9:
+			v, ok := q.PopFront()
+			if ok != (len(m) > 0) || ok && v != m[0] {
+				t.Fatalf("PopFront: %d %v", v, ok)
+			}
+			if ok {
+				m = m[1:]
+			}
+		default:
+			q.Compact(1)
+		}
+		if q.Len() != len(m) {
+			t.Fatalf("Len %d != %d", q.Len(), len(m))
+		}
+		if v, ok := q.PeekFront(); ok && v != m[0] {
+			t.Fatalf("PeekFront: %d != %d", v, m[0])
+		}
+		if nc := (len(m)+3)/4 + 1; q.nc > nc {
+			t.Fatalf("%d chunks for %d items", q.nc, len(m))
+		}
+	}
+}
+
+func TestChunkSpare(t *testing.T) {
+	q := newChunkQ[int](4)
+	for i := 0; i < 8; i++ {
+		q.PushBack(i)
+	}
+	for i := 0; i < 4; i++ {
+		q.PopFront()
+		q.Compact(1)
+	}
+	if q.spare == nil || q.Cap() != 8 {
+		t.Fatalf("Spare released early: cap %d", q.Cap())
+	}
+	spare := q.spare
+	for i := 0; i < 4; i++ {
+		q.PushBack(i)
+	}
+	if q.tail != spare {
+		t.Fatal("Spare not reused")
+	}
+	for i := 0; i < 4; i++ {
+		q.PopFront()
+		q.Compact(1)
+	}
+	if q.spare == nil {
+		t.Fatal("Spare released early")
+	}
+	for i := 0; i < 3; i++ {
+		q.Compact(1)
+	}
+	if q.spare != nil || q.Cap() != 4 {
+		t.Fatalf("Spare not released: cap %d", q.Cap())
+	}
+	for i := 0; i < 4; i++ {
+		q.PushBack(i)
+	}
+	for i := 0; i < 8; i++ {
+		q.PopFront()
+	}
+	q.Compact(1)
+	if q.spare != nil || q.Cap() != 4 {
+		t.Fatalf("Spare not released on empty: cap %d", q.Cap())
+	}
+}
+
+func TestChunkElastic(t *testing.T) {
+	const N = 10000
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer, o.Chunk = 0, 0, 64
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	if s := elc.Stats(); s.Len != N || s.Cap != (N+63)/64*64 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	close(elc.S)
+	if r := recvAll(t, elc.R); len(r) != N {
+		t.Fatalf("Received %d", len(r))
+	}
+	if s := elc.Stats(); s.Cap != 64 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	o.Spill = &Spill[T]{Threshold: 1, Codec: decCodec{}}
+	if _, err := NewElasticWithOptions(o); err != ErrChunk {
+		t.Fatalf("Chunk with Spill: err = %v", err)
+	}
+}
+
+// benchQueue pushes and then pops b.N elements to / from q, in
+// rounds of up to 1M elements.
+func benchQueue(b *testing.B, q queue[T]) {
+	b.ReportAllocs()
+	for n := b.N; n > 0; n -= 1 << 20 {
+		m := min(n, 1<<20)
+		for i := 0; i < m; i++ {
+			q.PushBack(T(i))
+		}
+		for i := 0; i < m; i++ {
+			q.PopFront()
+		}
+		q.Compact(1)
+	}
+}
+
+func BenchmarkQueueCQ(b *testing.B) {
+	benchQueue(b, newCQ[T](1, maxQSz))
+}
+
+func BenchmarkQueueChunk256(b *testing.B) {
+	benchQueue(b, newChunkQ[T](256))
+}
+
+func BenchmarkQueueChunk4096(b *testing.B) {
+	benchQueue(b, newChunkQ[T](4096))
+}
+
+func benchConChunk(b *testing.B, mode ShrinkMode) {
+	o := DefaultOptions[T]()
+	o.Mode, o.Chunk = mode, 1024
+	benchConOpt(b, o)
+}
+
+func BenchmarkConChunkShrink(b *testing.B) {
+	benchConChunk(b, Shrink)
+}
+
+func BenchmarkConChunkNoShrink(b *testing.B) {
+	benchConChunk(b, NoShrink)
+}
@@ -1,5 +1,3 @@
-// Auto-generated. !! DO NOT EDIT !!
-
 // Copyright (c) 2014, Nick Patavalis (npat@efault.net).
 // All rights reserved.
 // Use of this source code is governed by a BSD-style license that can
@@ -7,7 +5,7 @@
 
 package elastic
 
-// cQT is a circular queue.
+// cQ is a circular queue of E-typed elements.
 //
 // It is implemented with a slice and free running indexes. It starts
 // with a user specified initial size (which must be a power of 2) and
@@ -15,66 +13,66 @@ package elastic
 // more elements (up to a user specified maximum size).
 //
 // Queue operations are *NOT* thread safe.
-type cQT struct {
+type cQ[E any] struct {
 	sz    uint32 /* current queue size */
 	maxSz uint32 /* max queue size */
 	m     uint32 /* queue mask (sz - 1) */
 	s     uint32 /* start index */
 	e     uint32 /* end index */
-	b     []T    /* buffer */
+	b     []E    /* buffer */
 }
 
-// newCQT creates and returns a new circular queue.
+// newCQ creates and returns a new circular queue.
 //
 // The queue is initially allocated with space for sz elements. It can
 // grow, when required, to accomodate up to maxSz elements. Both sz
 // and maxSz *must* be powers of 2.
-func newCQT(sz, maxSz int) *cQT {
+func newCQ[E any](sz, maxSz int) *cQ[E] {
 	if sz <= 0 || uint32(sz)&(uint32(sz)-1) != 0 ||
 		uint32(maxSz)&(uint32(maxSz)-1) != 0 ||
 		maxSz < sz {
 		panic("Invalid Q size")
 	}
-	cq := &cQT{
+	cq := &cQ[E]{
 		sz: uint32(sz), maxSz: uint32(maxSz),
 		m: uint32(sz) - 1,
 		s: 0, e: 0,
 	}
-	cq.b = make([]T, sz)
+	cq.b = make([]E, sz)
 	return cq
 }
 
 // Empty tests if the queue is empty.
-func (cq *cQT) Empty() bool {
+func (cq *cQ[E]) Empty() bool {
 	return cq.s == cq.e
 }
 
 // Full tests if the queue is full.
-func (cq *cQT) Full() bool {
+func (cq *cQ[E]) Full() bool {
 	return cq.e-cq.s == cq.maxSz
 }
 
 // Len returns the number of elements waiting in the queue.
-func (cq *cQT) Len() int {
+func (cq *cQ[E]) Len() int {
 	return int(cq.e - cq.s)
 }
 
 // Cap returns the capacity of the queue (# of element slots currently
 // allocated).
-func (cq *cQT) Cap() int {
+func (cq *cQ[E]) Cap() int {
 	return int(cq.sz)
 }
 
 // MaxCap returns the maximum capacity of the queue (max # of element
 // allowed).
-func (cq *cQT) MaxCap() int {
+func (cq *cQ[E]) MaxCap() int {
 	return int(cq.maxSz)
 }
 
 // PeekFront returns the front (head) element of the queue, without
 // removing it. Returns ok == false if the list is empty (unable to
 // peek element), ok == true otherwise.
-func (cq *cQT) PeekFront() (el T, ok bool) {
+func (cq *cQ[E]) PeekFront() (el E, ok bool) {
 	if cq.s == cq.e {
 		return el, false
 	}
@@ -84,7 +82,7 @@ func (cq *cQT) PeekFront() (el T, ok bool) {
 // PeekBack returns the back (tail) element of the queue, without
 // removing it. Returns ok == false if the list is empty (unable to
 // peek element), ok == true otherwise.
-func (cq *cQT) PeekBack() (el T, ok bool) {
+func (cq *cQ[E]) PeekBack() (el E, ok bool) {
 	if cq.s == cq.e {
 		return el, false
 	}
@@ -94,8 +92,8 @@ func (cq *cQT) PeekBack() (el T, ok bool) {
 // PopHead removes the front (head) element from the queue and returns
 // it. Returns ok == false if the list was empty (unable to pop
 // element), ok == true otherwise.
-func (cq *cQT) PopFront() (el T, ok bool) {
-	var zero T
+func (cq *cQ[E]) PopFront() (el E, ok bool) {
+	var zero E
 	if cq.s == cq.e {
 		return zero, false
 	}
@@ -108,8 +106,8 @@ func (cq *cQT) PopFront() (el T, ok bool) {
 // PopBack removes the back (tail) element from the queue and returns
 // it. Returns ok == false if the list was empty (unable to pop
 // elemnt), ok == true otherwise.
-func (cq *cQT) PopBack() (el T, ok bool) {
-	var zero T
+func (cq *cQ[E]) PopBack() (el E, ok bool) {
+	var zero E
 	if cq.s == cq.e {
 		return zero, false
 	}
@@ -122,7 +120,7 @@ func (cq *cQT) PopBack() (el T, ok bool) {
 // PushBack adds element "el" to the back (tail) of the queue. Returns
 // ok == false if the list was full (unable to push element), ok ==
 // true otherwise.
-func (cq *cQT) PushBack(el T) (ok bool) {
+func (cq *cQ[E]) PushBack(el E) (ok bool) {
 	if cq.e-cq.s == cq.sz {
 		if cq.sz == cq.maxSz {
 			return false
@@ -137,7 +135,7 @@ func (cq *cQT) PushBack(el T) (ok bool) {
 // PushFront adds element "e" to the front (head) of the queue. Returns
 // ok == false if the list was full (unable to push element), ok ==
 // true otherwise.
-func (cq *cQT) PushFront(el T) (ok bool) {
+func (cq *cQ[E]) PushFront(el E) (ok bool) {
 	if cq.e-cq.s == cq.sz {
 		if cq.sz == cq.maxSz {
 			return false
@@ -171,7 +169,7 @@ func roundUp2(v uint32) uint32 {
 // nSz that satisfies all three: (1) nSz is a power of 2, (2) nSz >=
 // cq.Len(), (3) nSz >= sz. Compact does not affect the capacity
 // (maxSz) of the queue.
-func (cq *cQT) Compact(sz int) {
+func (cq *cQ[E]) Compact(sz int) {
 	if sz < 0 || uint32(sz) > cq.maxSz || uint32(sz)&(uint32(sz-1)) != 0 {
 		panic("Compact Q with invalid size")
 	}
@@ -188,8 +186,8 @@ func (cq *cQT) Compact(sz int) {
 // resize, resizes the queue to size sz. The caller *must* make sure
 // than sz satisfies all three: (1) sz >= cq.Len(), (2) sz is a power
 // of 2, (3) sz <= cq.maxSz
-func (cq *cQT) resize(sz uint32) {
-	b := make([]T, 0, sz)
+func (cq *cQ[E]) resize(sz uint32) {
+	b := make([]E, 0, sz)
 	si, ei := cq.s&cq.m, cq.e&cq.m
 	if si < ei {
 		b = append(b, cq.b[si:ei]...)
@@ -202,3 +200,135 @@ func (cq *cQT) resize(sz uint32) {
 	cq.sz = sz
 	cq.m = sz - 1
 }
+
+// At returns the i'th element of the queue (0 is the front). It
+// panics if i is out of range.
+func (cq *cQ[E]) At(i int) E {
+	if i < 0 || i >= int(cq.e-cq.s) {
+		panic("Q index out of range")
+	}
+	return cq.b[(cq.s+uint32(i))&cq.m]
+}
+
+// Set replaces the i'th element of the queue (0 is the front) with
+// el. It panics if i is out of range.
+func (cq *cQ[E]) Set(i int, el E) {
+	if i < 0 || i >= int(cq.e-cq.s) {
+		panic("Q index out of range")
+	}
+	cq.b[(cq.s+uint32(i))&cq.m] = el
+}
+
+// Insert inserts element el at position i, so that el becomes the
+// i'th element of the queue. Elements are moved from the side of the
+// queue that is closer to i. It panics if i is out of range (i ==
+// cq.Len() is allowed). Returns ok == false if the list was full
+// (unable to insert element), ok == true otherwise.
+func (cq *cQ[E]) Insert(i int, el E) (ok bool) {
+	n := int(cq.e - cq.s)
+	if i < 0 || i > n {
+		panic("Q index out of range")
+	}
+	if cq.e-cq.s == cq.sz {
+		if cq.sz == cq.maxSz {
+			return false
+		}
+		cq.resize(cq.sz << 1)
+	}
+	p := cq.s + uint32(i)
+	if i < n/2 {
+		cq.s--
+		p--
+		for j := cq.s; j != p; j++ {
+			cq.b[j&cq.m] = cq.b[(j+1)&cq.m]
+		}
+	} else {
+		for j := cq.e; j != p; j-- {
+			cq.b[j&cq.m] = cq.b[(j-1)&cq.m]
+		}
+		cq.e++
+	}
+	cq.b[p&cq.m] = el
+	return true
+}
+
+// Remove removes the i'th element from the queue and returns it.
+// Elements are moved from the side of the queue that is closer to
+// i. It panics if i is out of range.
+func (cq *cQ[E]) Remove(i int) (el E) {
+	var zero E
+	n := int(cq.e - cq.s)
+	if i < 0 || i >= n {
+		panic("Q index out of range")
+	}
+	p := cq.s + uint32(i)
+	el = cq.b[p&cq.m]
+	if i < n/2 {
+		for j := p; j != cq.s; j-- {
+			cq.b[j&cq.m] = cq.b[(j-1)&cq.m]
+		}
+		cq.b[cq.s&cq.m] = zero
+		cq.s++
+	} else {
+		cq.e--
+		for j := p; j != cq.e; j++ {
+			cq.b[j&cq.m] = cq.b[(j+1)&cq.m]
+		}
+		cq.b[cq.e&cq.m] = zero
+	}
+	return el
+}
+
+// Rotate rotates the queue n steps towards the front, so that the
+// n'th element becomes the front element. Negative values rotate
+// towards the back.
+func (cq *cQ[E]) Rotate(n int) {
+	l := int(cq.e - cq.s)
+	if l <= 1 {
+		return
+	}
+	if n %= l; n < 0 {
+		n += l
+	}
+	if n == 0 {
+		return
+	}
+	if cq.e-cq.s == cq.sz {
+		// Queue is full; rotation needs no moves.
+		cq.s += uint32(n)
+		cq.e += uint32(n)
+		return
+	}
+	if n <= l/2 {
+		for ; n > 0; n-- {
+			el, _ := cq.PopFront()
+			cq.b[cq.e&cq.m] = el
+			cq.e++
+		}
+	} else {
+		for n = l - n; n > 0; n-- {
+			el, _ := cq.PopBack()
+			cq.s--
+			cq.b[cq.s&cq.m] = el
+		}
+	}
+}
+
+// Clear removes all elements from the queue. The queue slice is not
+// shrinked.
+func (cq *cQ[E]) Clear() {
+	clear(cq.b)
+	cq.s, cq.e = 0, 0
+}
+
+// Reserve grows the queue slice, if required, so that it has space
+// for at least n elements (but not more than the max queue size).
+func (cq *cQ[E]) Reserve(n int) {
+	if n <= int(cq.sz) {
+		return
+	}
+	if n > int(cq.maxSz) {
+		n = int(cq.maxSz)
+	}
+	cq.resize(roundUp2(uint32(n)))
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// Coalescing is an elastic channel of E-typed elements where only the
+// latest item for every key is kept. The key of an item is extracted
+// by a user function. An item sent while another item with the same
+// key is queued replaces the queued item, and takes its position in
+// the queue. The backlog is, therefore, bounded by the number of
+// distinct keys.
+//
+// The receive side of a Coalescing channel is unbuffered, so that
+// every item delivered is the latest one for its key.
+type Coalescing[E any, K comparable] struct {
+	S chan<- E // Send direction.
+	R <-chan E // Receive direction.
+}
+
+// NewCoalescing creates and returns a new coalescing elastic channel,
+// using function key to extract the key of every item, and the
+// specified shrink mode.
+func NewCoalescing[E any, K comparable](key func(E) K,
+	mode ShrinkMode) Coalescing[E, K] {
+
+	cin := make(chan E, sendBuffer)
+	cout := make(chan E)
+	go coalesceRun(key, mode, cout, cin)
+	return Coalescing[E, K]{S: cin, R: cout}
+}
+
+// Map size above which a drained map is reallocated (if the shrink
+// mode allows it), since Go maps never shrink.
+const coalesceMapMin = 64
+
+// coalesceRun runs as the coalescing elastic channel goroutine. It
+// keeps a queue of keys, and the latest item for every key in a
+// map. Like engine.run, it tries to flush the input channel before
+// returning back to the select statement.
+func coalesceRun[E any, K comparable](key func(E) K, mode ShrinkMode,
+	cout chan<- E, cin <-chan E) {
+
+	var in <-chan E
+	var out chan<- E
+	var vi, vo E
+	var ok bool
+
+	q := newCQ[K](1, maxQSz)
+	m := make(map[K]E)
+	mmax := 0 // Max map size since last reallocated.
+	in = cin
+	for {
+		var k K
+		out = nil
+		if k, ok = q.PeekFront(); ok {
+			vo = m[k]
+			out = cout
+		} else if in == nil {
+			close(cout)
+			return
+		}
+		select {
+		case vi, ok = <-in:
+		inLoop:
+			for i := 1; ; i++ {
+				if !ok {
+					in = nil
+					break
+				}
+				ki := key(vi)
+				if _, dup := m[ki]; !dup {
+					q.PushBack(ki)
+					if len(m) >= mmax {
+						mmax = len(m) + 1
+					}
+				}
+				m[ki] = vi
+				if i == maxReceive {
+					break
+				}
+				select {
+				case vi, ok = <-in:
+				default:
+					break inLoop
+				}
+			}
+		case out <- vo:
+			q.PopFront()
+			delete(m, k)
+			if mustShrink(mode, q.Len(), q.Cap()) {
+				q.Compact(1)
+			}
+			if q.Empty() && mode != NoShrink &&
+				mmax > coalesceMapMin {
+				m, mmax = make(map[K]E), 0
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+type upd struct {
+	key string
+	val int
+}
+
+func TestCoalescing(t *testing.T) {
+	c := NewCoalescing(func(u upd) string { return u.key }, Shrink)
+	keys := []string{"a", "b", "c"}
+	for i := 0; i < 300; i++ {
+		c.S <- upd{keys[i%3], i}
+	}
+	c.S <- upd{"d", 1000}
+	c.S <- upd{"a", 1001}
+	for len(c.S) != 0 {
+		time.Sleep(1 * time.Millisecond)
+	}
+	close(c.S)
+	var r []upd
+	for u := range c.R {
+		r = append(r, u)
+	}
+	exp := []upd{{"a", 1001}, {"b", 298}, {"c", 299}, {"d", 1000}}
+	if len(r) != len(exp) {
+		t.Fatalf("Received %v", r)
+	}
+	for i := range r {
+		if r[i] != exp[i] {
+			t.Fatalf("Received %v", r)
+		}
+	}
+}
+
+func TestCoalescingData(t *testing.T) {
+	const N = 8192
+	c := NewCoalescing(func(v T) T { return v }, NoShrink)
+	end := make(chan int)
+	go produce(N, c.S, end)
+	go consume(N, c.R, end)
+	if r := <-end; r != N {
+		t.Fatalf("ret %d != %d", r, N)
+	}
+	if r := <-end; r != N {
+		t.Fatalf("ret %d != %d", r, N)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Delay is an elastic channel of E-typed elements where every item
+// has a release time. Items are sent using SendAt or SendAfter, and
+// are received from R once their release time arrives, in order of
+// release time. Items with the same release time are delivered in
+// the order they were sent.
+//
+// The receive side of a Delay channel is unbuffered, so that an item
+// is never handed out before an item with an earlier release time.
+type Delay[E any] struct {
+	R   <-chan E // Receive direction.
+	cin chan<- delayItem[E]
+	d   *delayEngine[E]
+}
+
+// delayItem is an item sent to a Delay channel.
+type delayItem[E any] struct {
+	at  time.Time // Release time.
+	seq uint64    // Send order, for items with the same release time.
+	v   E
+}
+
+// delayHeap is a min-heap of items, ordered by release time. It
+// implements heap.Interface.
+type delayHeap[E any] []delayItem[E]
+
+func (h delayHeap[E]) Len() int { return len(h) }
+
+func (h delayHeap[E]) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h delayHeap[E]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *delayHeap[E]) Push(x any) { *h = append(*h, x.(delayItem[E])) }
+
+func (h *delayHeap[E]) Pop() any {
+	old := *h
+	n := len(old) - 1
+	it := old[n]
+	old[n] = delayItem[E]{}
+	*h = old[:n]
+	return it
+}
+
+// delayEngine is the state of a Delay channel, owned by its
+// goroutine.
+type delayEngine[E any] struct {
+	mode ShrinkMode
+	h    delayHeap[E]
+	seq  uint64
+	cin  chan delayItem[E]
+	cout chan E
+	control
+	st   Stats
+	stop bool // Set by cancel.
+}
+
+// NewDelay creates and returns a new delay elastic channel, using the
+// specified shrink mode.
+func NewDelay[E any](mode ShrinkMode) Delay[E] {
+	d := &delayEngine[E]{
+		mode:    mode,
+		cin:     make(chan delayItem[E], sendBuffer),
+		cout:    make(chan E),
+		control: newControl(),
+	}
+	go d.run()
+	return Delay[E]{R: d.cout, cin: d.cin, d: d}
+}
+
+// SendAt sends v, to be released at time at. Items with a release
+// time in the past are released immediately. SendAt panics if the
+// channel has been closed.
+func (dc Delay[E]) SendAt(at time.Time, v E) {
+	dc.cin <- delayItem[E]{at: at, v: v}
+}
+
+// SendAfter sends v, to be released after duration dl.
+func (dc Delay[E]) SendAfter(dl time.Duration, v E) {
+	dc.SendAt(time.Now().Add(dl), v)
+}
+
+// Close closes the send direction of the channel. Items already sent
+// are still released at their release times; R is closed once all of
+// them have been delivered.
+func (dc Delay[E]) Close() {
+	close(dc.cin)
+}
+
+// Cancel stops the delay channel from the receiving side: the channel
+// goroutine discards its backlog, closes R and exits. Cancel returns
+// the items that were sent, but not delivered, in order of release
+// time. It returns nil if the channel has already been closed and
+// drained, or canceled.
+func (dc Delay[E]) Cancel() []E {
+	var vs []E
+	dc.d.do(func() { vs = dc.d.cancel() })
+	return vs
+}
+
+// Len returns the number of items waiting for their release time
+// (not counting items in the send-side buffer).
+func (dc Delay[E]) Len() int {
+	return dc.Stats().Len
+}
+
+// Stats returns a snapshot of the state and the counters of the delay
+// channel. Len is the number of items waiting for their release time,
+// and Cap the capacity of the backlog buffer. Fields that do not
+// apply to delay channels are zero.
+func (dc Delay[E]) Stats() Stats {
+	var s Stats
+	d := dc.d
+	d.view(func() {
+		s = d.st
+		s.Len, s.Cap = len(d.h), cap(d.h)
+		s.SendLen = len(d.cin)
+	})
+	return s
+}
+
+// push adds item it to the backlog.
+func (d *delayEngine[E]) push(it delayItem[E]) {
+	d.st.In++
+	it.seq = d.seq
+	d.seq++
+	c := cap(d.h)
+	heap.Push(&d.h, it)
+	if cap(d.h) != c {
+		d.st.Grows++
+	}
+	if len(d.h) > d.st.MaxLen {
+		d.st.MaxLen = len(d.h)
+	}
+}
+
+// pop removes the earliest item from the backlog, and compacts the
+// backlog buffer if required by the shrink mode.
+func (d *delayEngine[E]) pop() {
+	heap.Pop(&d.h)
+	if c := cap(d.h); c > 1 && mustShrink(d.mode, len(d.h), c) {
+		h := make(delayHeap[E], len(d.h), roundUp2(uint32(len(d.h))))
+		copy(h, d.h)
+		d.h = h
+		d.st.Compacts++
+	}
+}
+
+// cancel makes the goroutine exit, and returns the items that were
+// not delivered, in order of release time: items in the backlog and
+// in the send-side buffer.
+func (d *delayEngine[E]) cancel() []E {
+	d.stop = true
+	for i := len(d.cin); i > 0; i-- {
+		d.push(<-d.cin)
+	}
+	vs := make([]E, 0, len(d.h))
+	for len(d.h) > 0 {
+		vs = append(vs, heap.Pop(&d.h).(delayItem[E]).v)
+	}
+	d.h = nil
+	return vs
+}
+
+// run runs as the delay channel goroutine. A single timer is armed
+// for the earliest item in the backlog. Once the send side is closed,
+// the goroutine keeps releasing items until the backlog is drained.
+func (d *delayEngine[E]) run() {
+	var in <-chan delayItem[E]
+	var out chan<- E
+	var tc <-chan time.Time
+	var tmr *time.Timer
+	var vi delayItem[E]
+	var vo E
+	var ok bool
+
+	defer close(d.done)
+	defer func() {
+		if tmr != nil {
+			tmr.Stop()
+		}
+	}()
+	cin := d.cin
+	for {
+		in, out, tc = cin, nil, nil
+		if len(d.h) > 0 {
+			now := time.Now()
+			if at := d.h[0].at; at.After(now) {
+				if tmr == nil {
+					tmr = time.NewTimer(at.Sub(now))
+				} else {
+					tmr.Reset(at.Sub(now))
+				}
+				tc = tmr.C
+			} else {
+				vo, out = d.h[0].v, d.cout
+			}
+		} else if cin == nil {
+			close(d.cout)
+			return
+		}
+		select {
+		case vi, ok = <-in:
+		inLoop:
+			for i := 1; ; i++ {
+				if !ok {
+					cin = nil
+					break
+				}
+				d.push(vi)
+				if i == maxReceive {
+					break
+				}
+				select {
+				case vi, ok = <-in:
+				default:
+					break inLoop
+				}
+			}
+		case out <- vo:
+			d.st.Out++
+			d.pop()
+		case <-tc:
+		case f := <-d.ctl:
+			f()
+			if d.stop {
+				close(d.cout)
+				return
+			}
+		}
+	}
+}
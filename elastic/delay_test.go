@@ -0,0 +1,86 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	d := NewDelay[int](Shrink)
+	now := time.Now()
+	d.SendAt(now.Add(30*time.Millisecond), 3)
+	d.SendAt(now.Add(10*time.Millisecond), 1)
+	d.SendAt(now.Add(20*time.Millisecond), 2)
+	d.SendAt(now.Add(10*time.Millisecond), 11)
+	d.SendAt(now.Add(-time.Second), 0)
+	d.Close()
+	exp := []int{0, 1, 11, 2, 3}
+	i := 0
+	for v := range d.R {
+		if i >= len(exp) || v != exp[i] {
+			t.Fatalf("Received %d at %d", v, i)
+		}
+		if v != 0 && time.Now().Before(now.Add(10*time.Millisecond)) {
+			t.Fatalf("Received %d early", v)
+		}
+		i++
+	}
+	if i != len(exp) {
+		t.Fatalf("Received %d items", i)
+	}
+	s := d.Stats()
+	if s.In != 5 || s.Out != 5 || s.Len != 0 || s.MaxLen < 1 {
+		t.Fatalf("Stats: %+v", s)
+	}
+}
+
+func TestDelayLen(t *testing.T) {
+	d := NewDelay[int](NoShrink)
+	for i := 0; i < 10; i++ {
+		d.SendAfter(time.Hour, i)
+	}
+	for d.Len() != 10 {
+		time.Sleep(1 * time.Millisecond)
+	}
+	select {
+	case v := <-d.R:
+		t.Fatalf("Received %d", v)
+	case <-time.After(10 * time.Millisecond):
+	}
+	d.SendAfter(0, 100)
+	if v := <-d.R; v != 100 {
+		t.Fatalf("Received %d", v)
+	}
+	vs := d.Cancel()
+	if len(vs) != 10 {
+		t.Fatalf("Canceled %v", vs)
+	}
+	if _, ok := <-d.R; ok {
+		t.Fatalf("R not closed")
+	}
+	if vs := d.Cancel(); vs != nil {
+		t.Fatalf("Canceled %v again", vs)
+	}
+}
+
+func TestDelayData(t *testing.T) {
+	const N = 8192
+	d := NewDelay[T](Shrink)
+	end := make(chan int)
+	go func() {
+		// Same release time for all; order falls back to
+		// send order.
+		at := time.Now()
+		for i := 0; i < N; i++ {
+			d.SendAt(at, T(i))
+		}
+		d.Close()
+	}()
+	go consume(N, d.R, end)
+	if r := <-end; r != N {
+		t.Fatalf("ret %d != %d", r, N)
+	}
+	if s := d.Stats(); s.Len != 0 || s.Cap > 1 {
+		t.Fatalf("Stats: %+v", s)
+	}
+}
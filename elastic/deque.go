@@ -0,0 +1,55 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import "iter"
+
+// Deque is a double-ended queue of E-typed elements, with random
+// access. It is the circular queue used by elastic channels,
+// exported for direct use: it is implemented with a slice and free
+// running indexes, and it grows exponentially (doubles in size) when
+// required. Deque operations are *NOT* thread safe.
+//
+// Deques must be created with NewDeque.
+type Deque[E any] struct {
+	cQ[E]
+}
+
+// NewDeque creates and returns a new, empty, deque with space for at
+// least sz elements. The deque grows as required; it is shrinked only
+// by calling Compact.
+func NewDeque[E any](sz int) *Deque[E] {
+	if sz <= 0 || sz > maxQSz {
+		panic("Invalid Q size")
+	}
+	return &Deque[E]{*newCQ[E](int(roundUp2(uint32(sz))), maxQSz)}
+}
+
+// All returns an iterator over the indexes and the elements of the
+// deque, from front to back. The deque must not be modified during
+// the iteration, except with Set.
+func (d *Deque[E]) All() iter.Seq2[int, E] {
+	return func(yield func(int, E) bool) {
+		for i := 0; i < d.Len(); i++ {
+			if !yield(i, d.At(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the indexes and the elements of
+// the deque, from back to front. The deque must not be modified
+// during the iteration, except with Set.
+func (d *Deque[E]) Backward() iter.Seq2[int, E] {
+	return func(yield func(int, E) bool) {
+		for i := d.Len() - 1; i >= 0; i-- {
+			if !yield(i, d.At(i)) {
+				return
+			}
+		}
+	}
+}
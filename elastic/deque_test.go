@@ -0,0 +1,118 @@
+package elastic
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func checkDeque(t *testing.T, op string, d *Deque[int], m []int) {
+	t.Helper()
+	if d.Len() != len(m) {
+		t.Fatalf("%s: Len %d != %d", op, d.Len(), len(m))
+	}
+	for i, v := range d.All() {
+		if v != m[i] {
+			t.Fatalf("%s: At(%d) %d != %d", op, i, v, m[i])
+		}
+	}
+}
+
+func TestDequeRandom(t *testing.T) {
+	d := NewDeque[int](1)
+	var m []int
+	r := rand.New(rand.NewSource(1))
+	for k := 0; k < 20000; k++ {
+		var op string
+		switch x := r.Intn(10); {
+		case x < 2:
+			op = "PushBack"
+			d.PushBack(k)
+			m = append(m, k)
+		case x < 3:
+			op = "PushFront"
+			d.PushFront(k)
+			m = slices.Insert(m, 0, k)
+		case x < 5:
+			op = "Insert"
+			i := r.Intn(len(m) + 1)
+			d.Insert(i, k)
+			m = slices.Insert(m, i, k)
+		case x < 7:
+			if len(m) == 0 {
+				continue
+			}
+			op = "Remove"
+			i := r.Intn(len(m))
+			if v := d.Remove(i); v != m[i] {
+				t.Fatalf("Remove(%d) %d != %d", i, v, m[i])
+			}
+			m = slices.Delete(m, i, i+1)
+		case x < 8:
+			if len(m) == 0 {
+				continue
+			}
+			op = "Set"
+			i := r.Intn(len(m))
+			d.Set(i, -k)
+			m[i] = -k
+		case x < 9:
+			op = "Rotate"
+			n := r.Intn(2*len(m)+1) - len(m)
+			d.Rotate(n)
+			if len(m) > 0 {
+				n = ((n % len(m)) + len(m)) % len(m)
+				m = append(m[n:], m[:n]...)
+			}
+		default:
+			op = "Compact"
+			d.Compact(1)
+		}
+		checkDeque(t, op, d, m)
+	}
+}
+
+func TestDequeRotateFull(t *testing.T) {
+	d := NewDeque[int](8)
+	for i := 0; i < 8; i++ {
+		d.PushBack(i)
+	}
+	d.Rotate(3)
+	checkDeque(t, "Rotate", d, []int{3, 4, 5, 6, 7, 0, 1, 2})
+	d.Rotate(-5)
+	checkDeque(t, "Rotate", d, []int{6, 7, 0, 1, 2, 3, 4, 5})
+}
+
+func TestDequeMisc(t *testing.T) {
+	d := NewDeque[string](3)
+	if d.Cap() != 4 {
+		t.Fatalf("Cap %d", d.Cap())
+	}
+	d.Reserve(100)
+	if d.Cap() != 128 {
+		t.Fatalf("Cap after Reserve %d", d.Cap())
+	}
+	for _, s := range []string{"a", "b", "c"} {
+		d.PushBack(s)
+	}
+	var bw []string
+	for i, s := range d.Backward() {
+		if i == 0 {
+			break
+		}
+		bw = append(bw, s)
+	}
+	if !slices.Equal(bw, []string{"c", "b"}) {
+		t.Fatalf("Backward: %v", bw)
+	}
+	d.Clear()
+	if d.Len() != 0 || d.Cap() != 128 || !d.Empty() {
+		t.Fatalf("Clear: Len %d, Cap %d", d.Len(), d.Cap())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("At out of range did not panic")
+		}
+	}()
+	d.At(0)
+}
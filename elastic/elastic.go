@@ -8,9 +8,14 @@
 // https://github.com/npat-efault/musings/wiki/Elastic-channels
 package elastic
 
-// T is the element-type for the elastic channel.
+import "time"
+
+// T is the element-type for the ElasticT channel. It is kept for
+// compatibility; new code should use Elastic[E] with its own element
+// type.
 type T int
 
+// Default elastic channel parameters (see Options).
 const (
 	// Fixed buffer sizes at the send and the receive sides of the
 	// elastic channel.
@@ -34,12 +39,42 @@ const (
 	NoShrink                      // Never shrink.
 )
 
-// ElasticT is an elastic channel of T-typed elements.
-type ElasticT struct {
-	S chan<- T // Send direction.
-	R <-chan T // Receive direction.
+// Elastic is an elastic channel of E-typed elements.
+type Elastic[E any] struct {
+	S   chan<- E // Send direction.
+	R   <-chan E // Receive direction.
+	eng *engine[E]
+}
+
+// NewElastic creates and returns a new elastic channel of E-typed
+// elements, using the specified shrink mode.
+func NewElastic[E any](mode ShrinkMode) Elastic[E] {
+	o := DefaultOptions[E]()
+	o.Mode = mode
+	return mustElastic(o)
+}
+
+// newElastic returns the elastic channel served by engine en.
+func newElastic[E any](en *engine[E]) Elastic[E] {
+	return Elastic[E]{S: en.cin, R: en.cout, eng: en}
+}
+
+// Cancel stops the elastic channel from the receiving side: the
+// channel goroutine discards its queue, closes R and exits. Cancel
+// returns the items that were sent, but not delivered, in order. It
+// returns nil if the channel has already been closed or canceled.
+//
+// After Cancel, items sent to S are never delivered, and senders
+// block once the send-side buffer is full.
+func (e Elastic[E]) Cancel() []E {
+	var vs []E
+	e.eng.do(func() { vs = e.eng.cancel() })
+	return vs
 }
 
+// ElasticT is an elastic channel of T-typed elements.
+type ElasticT = Elastic[T]
+
 // NewElasticT creates and returns a new elastic channel, using the
 // default shrink mode (Shrink).
 func NewElasticT() ElasticT {
@@ -49,21 +84,17 @@ func NewElasticT() ElasticT {
 // NewElasticT1 creates and returns a new elastic channel, using the
 // specified shrink mode.
 func NewElasticT1(mode ShrinkMode) ElasticT {
-	cin := make(chan T, sendBuffer)
-	cout := make(chan T, receiveBuffer)
-	e := ElasticT{S: cin, R: cout}
-	go elasticRun1(mode, cout, cin)
-	return e
+	return NewElastic[T](mode)
 }
 
 // elasticRun runs as the elastic channel goroutine.
-func elasticRun(mode ShrinkMode, cout chan<- T, cin <-chan T) {
-	var in <-chan T
-	var out chan<- T
-	var vi, vo T
+func elasticRun[E any](mode ShrinkMode, cout chan<- E, cin <-chan E) {
+	var in <-chan E
+	var out chan<- E
+	var vi, vo E
 	var ok bool
 
-	q := newCQT(1, maxQSz)
+	q := newCQ[E](1, maxQSz)
 	in, out = cin, nil
 	for {
 		select {
@@ -103,37 +134,423 @@ func elasticRun(mode ShrinkMode, cout chan<- T, cin <-chan T) {
 	}
 }
 
-// elasticRun1 is a replacement (faster) implementation of elasticRun
-// that tries to flush the input channel (cin) and the internal queue
-// before returning back to the select statement. It takes advantage
-// of the fact that select statements seem to have considerable
-// overhead over single-channel reveive / select statements.
-func elasticRun1(mode ShrinkMode, cout chan<- T, cin <-chan T) {
-	var in <-chan T
-	var out chan<- T
-	var vi, vo T
+// queue is the interface of the internal queue of elastic channels.
+// It is implemented by cQ, chunkQ and spillQ.
+type queue[E any] interface {
+	Len() int
+	Cap() int
+	PeekFront() (el E, ok bool)
+	PopFront() (el E, ok bool)
+	PushBack(el E) (ok bool)
+	PushFront(el E) (ok bool)
+	Compact(sz int)
+}
+
+// engine is the state of the elastic channel goroutine. It is owned
+// by the goroutine; other goroutines access it only through do and
+// view.
+type engine[E any] struct {
+	o    Options[E]
+	q    queue[E]
+	cq   *cQ[E] // Same as q, if q is a plain circular queue.
+	cin  chan E
+	cout chan E
+	control
+	st    Stats
+	bytes int       // Bytes queued (if there is a sizer).
+	blkd  bool      // Input is blocked (Block policy).
+	mblk  bool      // Input may be blocked (bounded Block policy, group).
+	stop  bool      // Goroutine must exit (Cancel called).
+	marks chan Mark // Watermark notifications.
+	shr   ShrinkPolicy
+	tmr   *time.Timer      // Shrink recheck timer.
+	tc    <-chan time.Time // Recheck timer channel, or nil.
+	sq    *spillQ[E]       // Spill queue, if spilling.
+	tq    *ttlQ[E]         // Timestamped queue, if TTL is set.
+	etmr  *time.Timer      // Expiry timer.
+	ec    <-chan time.Time // Expiry timer channel, or nil.
+	edl   int64            // Deadline the expiry timer is armed for.
+	gm    *gMember         // Group membership, if in a group.
+	fl    []chan struct{}  // Pending Flush calls.
+	ftmr  *time.Timer      // Flush poll timer.
+	fc    <-chan time.Time // Flush poll timer channel, or nil.
+}
+
+// newEngine creates and returns a new engine, and starts the
+// elastic channel goroutine that runs it.
+func newEngine[E any](o Options[E]) (*engine[E], error) {
+	en := &engine[E]{
+		o:       o,
+		cin:     make(chan E, o.SendBuffer),
+		cout:    make(chan E, o.ReceiveBuffer),
+		control: newControl(),
+		shr:     o.Shrinker,
+	}
+	en.mblk = o.Overflow == Block &&
+		(o.MaxQueue < maxQSz || o.MaxBytes > 0) || o.Group != nil
+	if o.HighMark > 0 {
+		en.marks = make(chan Mark, 1)
+	}
+	if o.Group != nil {
+		en.gm = o.Group.join()
+	}
+	if en.shr == nil {
+		en.shr = o.Mode
+	}
+	if o.Spill != nil {
+		sq, err := newSpillQ(*o.Spill, o.InitQueue)
+		if err != nil {
+			return nil, err
+		}
+		en.q, en.sq = sq, sq
+	} else if o.Chunk > 0 {
+		en.q = newChunkQ[E](o.Chunk)
+	} else {
+		en.q = newCQ[E](o.InitQueue, maxQSz)
+	}
+	if o.TTL > 0 {
+		en.tq = newTTLQ(en.q)
+		en.q = en.tq
+	}
+	en.cq, _ = en.q.(*cQ[E])
+	go en.run()
+	return en, nil
+}
+
+// control lets other goroutines run functions from a channel
+// goroutine, which receives them from ctl.
+type control struct {
+	ctl  chan func()   // Requests run by the goroutine.
+	done chan struct{} // Closed when the goroutine exits.
+}
+
+// newControl returns a new control.
+func newControl() control {
+	return control{ctl: make(chan func()), done: make(chan struct{})}
+}
+
+// do runs f from the channel goroutine and waits for it to complete.
+// Returns false, without running f, if the goroutine has exited.
+func (c *control) do(f func()) bool {
+	ack := make(chan struct{})
+	select {
+	case c.ctl <- func() { f(); close(ack) }:
+		<-ack
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// view runs f, which must not modify the goroutine's state, from the
+// channel goroutine, or directly if the goroutine has exited.
+func (c *control) view(f func()) {
+	if !c.do(f) {
+		f()
+	}
+}
+
+// peek, pushBack, popFront and lenCap are used in the per-item path.
+// They avoid the (relatively expensive) calls through the queue
+// interface when the queue is a plain circular queue.
+
+// peek returns the front element of the queue, without removing it.
+func (en *engine[E]) peek() (E, bool) {
+	if en.cq != nil {
+		return en.cq.PeekFront()
+	}
+	return en.q.PeekFront()
+}
+
+// pushBack adds v to the back of the queue. Returns the new queue
+// length, and if the queue buffer grew. Returns ok == false if v
+// could not be added.
+func (en *engine[E]) pushBack(v E) (l int, grew, ok bool) {
+	if cq := en.cq; cq != nil {
+		c := cq.Cap()
+		if !cq.PushBack(v) {
+			return 0, false, false
+		}
+		return cq.Len(), cq.Cap() != c, true
+	}
+	c := en.q.Cap()
+	if !en.q.PushBack(v) {
+		return 0, false, false
+	}
+	return en.q.Len(), en.q.Cap() != c, true
+}
+
+// popFront removes the front element from the queue and returns it.
+func (en *engine[E]) popFront() E {
+	if en.cq != nil {
+		v, _ := en.cq.PopFront()
+		return v
+	}
+	v, _ := en.q.PopFront()
+	return v
+}
+
+// lenCap returns the length and the capacity of the queue.
+func (en *engine[E]) lenCap() (l, c int) {
+	if en.cq != nil {
+		return en.cq.Len(), en.cq.Cap()
+	}
+	return en.q.Len(), en.q.Cap()
+}
+
+// full tests if the queue has reached its maximum length, or its
+// maximum size in bytes.
+func (en *engine[E]) full() bool {
+	return en.q.Len() >= en.o.MaxQueue ||
+		en.o.MaxBytes > 0 && en.bytes >= en.o.MaxBytes
+}
+
+// fits tests if an item of sz bytes can be added to the queue
+// without exceeding its maximum length, or its maximum size in
+// bytes.
+func (en *engine[E]) fits(sz int) bool {
+	return en.q.Len() < en.o.MaxQueue &&
+		(en.o.MaxBytes == 0 || en.bytes+sz <= en.o.MaxBytes)
+}
+
+// sizeOf returns the size of v in bytes, or 0 if there is no sizer.
+func (en *engine[E]) sizeOf(v E) int {
+	if en.o.Sizer == nil {
+		return 0
+	}
+	return en.o.Sizer(v)
+}
+
+// blocking tests if the goroutine must stop receiving from the input
+// channel, because the queue is full and the policy is Block, or
+// because its group has blocked it. It counts the times the input
+// gets blocked.
+func (en *engine[E]) blocking() bool {
+	if !en.mblk {
+		return false
+	}
+	if (en.o.Overflow != Block || !en.full()) && !en.gBlocked() {
+		en.blkd = false
+		return false
+	}
+	if !en.blkd {
+		en.blkd = true
+		en.st.Drops.Blocked++
+	}
+	return true
+}
+
+// push adds vi to the back of the queue, applying the overflow
+// policy if it does not fit. With the Block policy, push must not be
+// called when the queue is full; the item is then added even if it
+// does not fit.
+func (en *engine[E]) push(vi E) {
+	en.st.In++
+	sz := en.sizeOf(vi)
+	if en.gReject(sz) {
+		en.st.Drops.Group++
+		en.drop(vi)
+		return
+	}
+	if en.o.Overflow != Block && !en.fits(sz) {
+		// An item larger than MaxBytes never fits; drop it
+		// without evicting the backlog for it.
+		if en.o.Overflow == DropOldest &&
+			(en.o.MaxBytes == 0 || sz <= en.o.MaxBytes) {
+			for en.q.Len() > 0 && !en.fits(sz) {
+				vo, _ := en.q.PopFront()
+				en.bytes -= en.sizeOf(vo)
+				en.st.Drops.Oldest++
+				en.drop(vo)
+			}
+		}
+		if !en.fits(sz) {
+			en.st.Drops.Newest++
+			en.drop(vi)
+			return
+		}
+	}
+	l, grew, ok := en.pushBack(vi)
+	if !ok {
+		en.st.Drops.Failed++
+		en.drop(vi)
+		if en.marks != nil {
+			en.mark()
+		}
+		if en.gm != nil {
+			en.gSync()
+		}
+		return
+	}
+	if grew {
+		en.st.Grows++
+	}
+	if en.bytes += sz; en.bytes > en.st.PeakBytes {
+		en.st.PeakBytes = en.bytes
+	}
+	if l > en.st.MaxLen {
+		en.st.MaxLen = l
+	}
+	if en.marks != nil {
+		en.mark()
+	}
+	if en.gm != nil {
+		en.gSync()
+	}
+}
+
+// drop hands a dropped item to the onDrop callback, if any.
+func (en *engine[E]) drop(v E) {
+	if en.o.OnDrop != nil {
+		en.o.OnDrop(v)
+	}
+}
+
+// pop removes the front element from the queue and returns it. It
+// then shrinks the queue, as dictated by the shrink policy.
+func (en *engine[E]) pop() E {
+	v := en.popFront()
+	en.bytes -= en.sizeOf(v)
+	en.shrink()
+	if en.marks != nil {
+		en.mark()
+	}
+	if en.gm != nil {
+		en.gSync()
+	}
+	return v
+}
+
+// shrink asks the shrink policy if the queue must be compacted, and
+// compacts it. If the policy asks to be called again, it arms the
+// recheck timer.
+func (en *engine[E]) shrink() {
+	if en.o.Shrinker == nil {
+		// Plain shrink mode; avoid the interface call.
+		if l, c := en.lenCap(); mustShrink(en.o.Mode, l, c) {
+			en.compact(1)
+		}
+		return
+	}
+	sz, after := en.shr.Shrink(en.q.Len(), en.q.Cap())
+	if sz > 0 {
+		en.compact(sz)
+	}
+	if after > 0 && en.tc == nil {
+		if en.tmr == nil {
+			en.tmr = time.NewTimer(after)
+		} else {
+			en.tmr.Reset(after)
+		}
+		en.tc = en.tmr.C
+	}
+}
+
+// compact compacts the queue down to size sz, but not below its
+// initial size.
+func (en *engine[E]) compact(sz int) {
+	if sz < en.o.InitQueue {
+		sz = en.o.InitQueue
+	} else if sz > maxQSz {
+		sz = maxQSz
+	}
+	c := en.q.Cap()
+	en.q.Compact(int(roundUp2(uint32(sz))))
+	if en.q.Cap() != c {
+		en.st.Compacts++
+	}
+}
+
+// cancel makes the goroutine exit, and returns the items that were
+// not delivered, in order: items in the receive-side buffer, the
+// internal queue, and the send-side buffer.
+func (en *engine[E]) cancel() []E {
+	en.stop = true
+	vs := make([]E, 0, len(en.cout)+en.q.Len()+len(en.cin))
+	for drained := false; !drained; {
+		select {
+		case v := <-en.cout:
+			vs = append(vs, v)
+		default:
+			drained = true
+		}
+	}
+	for {
+		v, ok := en.q.PopFront()
+		if !ok {
+			break
+		}
+		vs = append(vs, v)
+	}
+	en.bytes = 0
+	if en.gm != nil {
+		en.gSync()
+	}
+	for i := len(en.cin); i > 0; i-- {
+		vs = append(vs, <-en.cin)
+	}
+	return vs
+}
+
+// run runs as the elastic channel goroutine. It is a replacement
+// (faster) implementation of elasticRun that tries to flush the
+// input channel (cin) and the internal queue before returning back
+// to the select statement. It takes advantage of the fact that
+// select statements seem to have considerable overhead over
+// single-channel reveive / select statements. Unlike elasticRun, the
+// element being sent is kept at the front of the queue until it is
+// delivered.
+func (en *engine[E]) run() {
+	var in <-chan E
+	var out chan<- E
+	var vi, vo E
 	var ok bool
 
-	q := newCQT(1, maxQSz)
-	in, out = cin, nil
+	defer close(en.done)
+	defer en.flushDone()
+	if en.sq != nil {
+		defer en.sq.Close()
+	}
+	var ctxDone <-chan struct{}
+	if en.o.Context != nil {
+		ctxDone = en.o.Context.Done()
+	}
+	var gsig <-chan struct{}
+	if en.gm != nil {
+		gsig = en.gm.sig
+		defer en.o.Group.leave(en.gm)
+	}
+	cin := en.cin
 	for {
+		in, out = cin, nil
+		if en.blocking() {
+			in = nil
+		}
+		if en.tq != nil {
+			en.expire()
+		}
+		if len(en.fl) > 0 {
+			en.flushCheck()
+		}
+		if vo, ok = en.peek(); ok {
+			if !en.st.Paused {
+				out = en.cout
+			}
+		} else if cin == nil {
+			close(en.cout)
+			return
+		}
 		select {
 		case vi, ok = <-in:
 		inLoop:
-			for i := 0; i < maxReceive; i++ {
+			for i := 1; ; i++ {
 				if !ok {
-					if out == nil {
-						close(cout)
-						return
-					}
-					in = nil
+					cin = nil
 					break
 				}
-				if out == nil {
-					vo = vi
-					out = cout
-				} else {
-					q.PushBack(vi)
+				en.push(vi)
+				if i == en.o.MaxReceive || en.blocking() {
+					break
 				}
 				select {
 				case vi, ok = <-in:
@@ -144,21 +561,13 @@ func elasticRun1(mode ShrinkMode, cout chan<- T, cin <-chan T) {
 		case out <- vo:
 		outLoop:
 			for {
-				vo, ok = q.PopFront()
-				if !ok {
-					if in == nil {
-						close(cout)
-						return
-					}
-					out = nil
-					if mode == ShrinkEmpty {
-						q.Compact(1)
-					}
+				en.st.Out++
+				en.pop()
+				if en.tq != nil {
+					en.expire()
 				}
-				if mode == Shrink {
-					if q.Len() < q.Cap()>>1 {
-						q.Compact(1)
-					}
+				if vo, ok = en.peek(); !ok {
+					break
 				}
 				select {
 				case out <- vo:
@@ -166,6 +575,27 @@ func elasticRun1(mode ShrinkMode, cout chan<- T, cin <-chan T) {
 					break outLoop
 				}
 			}
+		case f := <-en.ctl:
+			f()
+			if en.stop {
+				close(en.cout)
+				return
+			}
+		case <-en.tc:
+			en.tc = nil
+			en.shrink()
+		case <-gsig:
+			if en.gm.flags.Load()&gTrim != 0 {
+				en.gTrim()
+			}
+		case <-en.ec:
+			en.ec, en.edl = nil, 0
+		case <-en.fc:
+			en.fc = nil
+		case <-ctxDone:
+			en.cancel()
+			close(en.cout)
+			return
 		}
 	}
 }
@@ -67,6 +67,57 @@ func TestData(t *testing.T) {
 	}
 }
 
+type rec struct {
+	id   int
+	name string
+}
+
+func TestGeneric(t *testing.T) {
+	const N = 8192
+	for _, mode := range []ShrinkMode{Shrink, ShrinkEmpty, NoShrink} {
+		elc := NewElastic[*rec](mode)
+		go func() {
+			for i := 0; i < N; i++ {
+				elc.S <- &rec{id: i}
+			}
+			close(elc.S)
+		}()
+		i := 0
+		for r := range elc.R {
+			if r.id != i {
+				t.Fatalf("Mode %d: got %d != %d", mode, r.id, i)
+			}
+			i++
+		}
+		if i != N {
+			t.Fatalf("Mode %d: received %d != %d", mode, i, N)
+		}
+	}
+}
+
+func TestCQ(t *testing.T) {
+	q := newCQ[rec](1, 1024)
+	for i := 0; i < 100; i++ {
+		q.PushBack(rec{id: i})
+	}
+	q.PushFront(rec{id: -1})
+	if q.Len() != 101 || q.Cap() != 128 {
+		t.Fatalf("Len/Cap = %d/%d", q.Len(), q.Cap())
+	}
+	for i := -1; i < 50; i++ {
+		if r, ok := q.PopFront(); !ok || r.id != i {
+			t.Fatalf("PopFront: %v %v != %d", r, ok, i)
+		}
+	}
+	q.Compact(1)
+	if q.Cap() != 64 {
+		t.Fatalf("Cap after Compact = %d", q.Cap())
+	}
+	if r, ok := q.PopBack(); !ok || r.id != 99 {
+		t.Fatalf("PopBack: %v %v", r, ok)
+	}
+}
+
 func benchFixed(b *testing.B, buffer int) {
 	c := make(chan T, buffer)
 	end := make(chan int)
@@ -131,3 +182,55 @@ func BenchmarkConShrinkEmpty(b *testing.B) {
 func BenchmarkConNoShrink(b *testing.B) {
 	benchCon(b, NoShrink)
 }
+
+func benchSPSCCon(b *testing.B, mode ShrinkMode) {
+	q := NewSPSC[T](mode)
+	b.ResetTimer()
+	go func() {
+		for i := 0; i < b.N; i++ {
+			q.Send(T(i))
+		}
+		q.Close()
+	}()
+	for i := 0; i < b.N; i++ {
+		if v, _ := q.Recv(); v != T(i) {
+			b.Fatalf("Received %d != %d", v, i)
+		}
+	}
+	b.StopTimer()
+}
+
+func BenchmarkConSPSCShrink(b *testing.B) {
+	benchSPSCCon(b, Shrink)
+}
+
+func BenchmarkConSPSCShrinkEmpty(b *testing.B) {
+	benchSPSCCon(b, ShrinkEmpty)
+}
+
+func BenchmarkConSPSCNoShrink(b *testing.B) {
+	benchSPSCCon(b, NoShrink)
+}
+
+func benchConBatch(b *testing.B, mode ShrinkMode) {
+	elc := NewElasticT1(mode)
+	end := make(chan int)
+	b.ResetTimer()
+	go produceBatch(b.N, elc, end)
+	go consumeBatch(b.N, elc, end)
+	<-end
+	<-end
+	b.StopTimer()
+}
+
+func BenchmarkConBatchShrink(b *testing.B) {
+	benchConBatch(b, Shrink)
+}
+
+func BenchmarkConBatchShrinkEmpty(b *testing.B) {
+	benchConBatch(b, ShrinkEmpty)
+}
+
+func BenchmarkConBatchNoShrink(b *testing.B) {
+	benchConBatch(b, NoShrink)
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import (
+	"context"
+	"time"
+)
+
+// Interval for checking if the receive-side buffer has been drained.
+// Receives from R do not wake up the elastic channel goroutine, so it
+// has to poll.
+const flushPoll = 1 * time.Millisecond
+
+// Flush waits until the elastic channel is empty: the send-side
+// buffer, the internal queue and the receive-side buffer are all
+// empty at the same time. When Flush returns nil, every item sent to
+// S before Flush was called has been received from R (or dropped).
+// Items sent concurrently with Flush may delay it, for as long as
+// the channel does not become empty. Flush returns ctx.Err() if ctx
+// is done first.
+func (e Elastic[E]) Flush(ctx context.Context) error {
+	en := e.eng
+	var c chan struct{}
+	if en.do(func() { c = en.flush() }) && c == nil {
+		return nil
+	}
+	if c != nil {
+		select {
+		case <-c:
+		case <-ctx.Done():
+			en.do(func() { en.unflush(c) })
+			return ctx.Err()
+		}
+	}
+	// The goroutine has exited. Wait for the items it has
+	// left in the receive-side buffer.
+	var tmr *time.Timer
+	for len(en.cout) > 0 {
+		if tmr == nil {
+			tmr = time.NewTimer(flushPoll)
+			defer tmr.Stop()
+		} else {
+			tmr.Reset(flushPoll)
+		}
+		select {
+		case <-tmr.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// empty tests if the elastic channel is empty.
+func (en *engine[E]) empty() bool {
+	return len(en.cin) == 0 && en.q.Len() == 0 && len(en.cout) == 0
+}
+
+// flush returns nil if the channel is empty. Otherwise it returns a
+// new channel, which is closed once the channel becomes empty, or the
+// goroutine exits.
+func (en *engine[E]) flush() chan struct{} {
+	if en.empty() {
+		return nil
+	}
+	c := make(chan struct{})
+	en.fl = append(en.fl, c)
+	return c
+}
+
+// unflush removes flush channel c, if it is still pending.
+func (en *engine[E]) unflush(c chan struct{}) {
+	for i, c1 := range en.fl {
+		if c1 == c {
+			en.fl = append(en.fl[:i], en.fl[i+1:]...)
+			break
+		}
+	}
+}
+
+// flushCheck closes the pending flush channels, if the channel is
+// empty. If only the receive-side buffer is not, it arms the flush
+// poll timer to check again. It must only be called with flushes
+// pending (len(en.fl) > 0).
+func (en *engine[E]) flushCheck() {
+	if en.empty() {
+		en.flushDone()
+		return
+	}
+	if en.fc == nil && len(en.cin) == 0 && en.q.Len() == 0 {
+		if en.ftmr == nil {
+			en.ftmr = time.NewTimer(flushPoll)
+		} else {
+			en.ftmr.Reset(flushPoll)
+		}
+		en.fc = en.ftmr.C
+	}
+}
+
+// flushDone closes all pending flush channels.
+func (en *engine[E]) flushDone() {
+	for _, c := range en.fl {
+		close(c)
+	}
+	en.fl = nil
+}
@@ -0,0 +1,50 @@
+package elastic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFlush(t *testing.T) {
+	const N = 1000
+	elc := NewElasticT()
+	ctx := context.Background()
+	if err := elc.Flush(ctx); err != nil {
+		t.Fatalf("Flush empty: %v", err)
+	}
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	go func() {
+		for range elc.R {
+			time.Sleep(10 * time.Microsecond)
+		}
+	}()
+	if err := elc.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if s := elc.Stats(); s.Out != N || s.RecvLen != 0 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	close(elc.S)
+}
+
+func TestFlushTimeout(t *testing.T) {
+	elc := NewElasticT()
+	elc.S <- 1
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if err := elc.Flush(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(elc.eng.fl) != 0 {
+		t.Fatalf("Flush channel not removed")
+	}
+	close(elc.S)
+	<-elc.R
+	if err := elc.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush closed: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// GroupPolicy type encodes values that specify what an ElasticGroup
+// does when it is over its budget.
+type GroupPolicy int
+
+const (
+	// GroupPolicy values.
+	BlockLargest GroupPolicy = iota // Block the sender of the largest queue.
+	DropLargest                     // Drop items from the largest queue.
+	Reject                          // Drop new items.
+)
+
+// ElasticGroup is a memory budget shared by a group of elastic
+// channels. Channels join a group by setting Options.Group. The
+// budget is the total number of items, or bytes (the channels must
+// then have a Sizer), in the internal queues of all the channels in
+// the group. The budget is soft: it may be exceeded for a little
+// while, since the group reacts after it is exceeded (except for the
+// Reject policy).
+type ElasticGroup struct {
+	budget int64
+	bytes  bool
+	policy GroupPolicy
+	total  atomic.Int64 // Items, or bytes, used by all members.
+	nblk   atomic.Int32 // Number of blocked members.
+	mu     sync.Mutex   // Protects ms.
+	ms     []*gMember
+}
+
+// ErrGroup is returned by NewElasticWithOptions if the channel
+// cannot join its group.
+var ErrGroup = errors.New("elastic: invalid group options")
+
+// NewElasticGroup creates and returns a new group with the given
+// budget, counted in bytes if bytes is true, and in items
+// otherwise.
+func NewElasticGroup(budget int, bytes bool,
+	policy GroupPolicy) *ElasticGroup {
+
+	if budget <= 0 {
+		panic("Invalid group budget")
+	}
+	if policy < BlockLargest || policy > Reject {
+		panic("Invalid group policy")
+	}
+	return &ElasticGroup{budget: int64(budget), bytes: bytes, policy: policy}
+}
+
+// Used returns the items, or bytes, currently used by the channels in
+// the group.
+func (g *ElasticGroup) Used() int {
+	return int(g.total.Load())
+}
+
+// gMember is the part of an engine that is visible to its group.
+type gMember struct {
+	used  atomic.Int64  // Items, or bytes, used.
+	flags atomic.Uint32 // gBlock, gTrim.
+	sig   chan struct{} // Pokes the engine when flags change.
+}
+
+// gMember flags.
+const (
+	gBlock = 1 << iota // Member must stop receiving.
+	gTrim              // Member must drop items.
+)
+
+// join adds a new member to the group, and returns it.
+func (g *ElasticGroup) join() *gMember {
+	m := &gMember{sig: make(chan struct{}, 1)}
+	g.mu.Lock()
+	g.ms = append(g.ms, m)
+	g.mu.Unlock()
+	return m
+}
+
+// leave removes member m from the group, and releases its usage.
+func (g *ElasticGroup) leave(m *gMember) {
+	g.mu.Lock()
+	for i, m1 := range g.ms {
+		if m1 == m {
+			g.ms = append(g.ms[:i], g.ms[i+1:]...)
+			break
+		}
+	}
+	if m.flags.Load()&gBlock != 0 {
+		g.nblk.Add(-1)
+	}
+	g.mu.Unlock()
+	g.add(m, -m.used.Load())
+}
+
+// over tests if the group is over its budget.
+func (g *ElasticGroup) over() bool {
+	return g.total.Load() > g.budget
+}
+
+// fits tests if n more items, or bytes, fit in the budget.
+func (g *ElasticGroup) fits(n int64) bool {
+	return g.total.Load()+n <= g.budget
+}
+
+// add adds delta to the usage of member m. If this brings the group
+// back within its budget, blocked members are unblocked.
+func (g *ElasticGroup) add(m *gMember, delta int64) {
+	m.used.Add(delta)
+	t := g.total.Add(delta)
+	if delta < 0 && t <= g.budget && g.nblk.Load() > 0 {
+		g.unblock()
+	}
+}
+
+// poke sets flag f on member m, and wakes up its engine.
+func (m *gMember) poke(f uint32) {
+	m.flags.Or(f)
+	select {
+	case m.sig <- struct{}{}:
+	default:
+	}
+}
+
+// unblock unblocks all blocked members.
+func (g *ElasticGroup) unblock() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, m := range g.ms {
+		if m.flags.Load()&gBlock != 0 {
+			m.flags.And(^uint32(gBlock))
+			g.nblk.Add(-1)
+			m.poke(0)
+		}
+	}
+}
+
+// overflow applies the group policy, after member self has made the
+// group go over its budget. Returns true if self must drop items
+// (DropLargest, self is the largest member).
+func (g *ElasticGroup) overflow(self *gMember) bool {
+	if g.policy == Reject {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var lm *gMember
+	for _, m := range g.ms {
+		if g.policy == BlockLargest && m.flags.Load()&gBlock != 0 {
+			continue
+		}
+		if lm == nil || m.used.Load() > lm.used.Load() {
+			lm = m
+		}
+	}
+	if lm == nil {
+		// All blocked (BlockLargest). Block self.
+		lm = self
+	}
+	switch g.policy {
+	case BlockLargest:
+		if lm.flags.Load()&gBlock == 0 {
+			// Count the block before re-checking the budget:
+			// a concurrent add either sees the count and
+			// unblocks (after we release mu), or has already
+			// brought the group back within its budget.
+			g.nblk.Add(1)
+			if !g.over() {
+				g.nblk.Add(-1)
+				return false
+			}
+			lm.poke(gBlock)
+		}
+	case DropLargest:
+		if lm == self {
+			return true
+		}
+		lm.poke(gTrim)
+	}
+	return false
+}
+
+// gUsage returns the engine's current usage, in group units.
+func (en *engine[E]) gUsage() int64 {
+	if en.o.Group.bytes {
+		return int64(en.bytes)
+	}
+	return int64(en.q.Len())
+}
+
+// gSync reports changes of the engine's usage to its group, and
+// applies the group policy if the group goes over its budget. It must
+// only be called if the engine is in a group (en.gm != nil).
+func (en *engine[E]) gSync() {
+	g := en.o.Group
+	delta := en.gUsage() - en.gm.used.Load()
+	if delta == 0 {
+		return
+	}
+	g.add(en.gm, delta)
+	if delta > 0 && g.over() && g.overflow(en.gm) {
+		en.gTrim()
+	}
+}
+
+// gReject tests if an item of sz bytes must be rejected, because it
+// does not fit in the group budget (Reject policy).
+func (en *engine[E]) gReject(sz int) bool {
+	g := en.o.Group
+	if g == nil || g.policy != Reject {
+		return false
+	}
+	n := int64(1)
+	if g.bytes {
+		n = int64(sz)
+	}
+	return !g.fits(n)
+}
+
+// gTrim drops items from the front of the queue, while the group is
+// over its budget.
+func (en *engine[E]) gTrim() {
+	g := en.o.Group
+	en.gm.flags.And(^uint32(gTrim))
+	for g.over() && en.q.Len() > 0 {
+		v := en.pop()
+		en.st.Drops.Group++
+		en.drop(v)
+	}
+}
+
+// gBlocked tests if the engine has been blocked by its group.
+func (en *engine[E]) gBlocked() bool {
+	return en.gm != nil && en.gm.flags.Load()&gBlock != 0
+}
@@ -0,0 +1,108 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+// inGroup returns an option setter that makes a channel a member
+// of group g.
+func inGroup(g *ElasticGroup) func(o *Options[T]) {
+	return func(o *Options[T]) { o.Group = g }
+}
+
+// trySend sends up to n items to elc, and returns the number sent
+// before blocking. It returns after the engine has queued them.
+func trySend(elc ElasticT, n int) int {
+	for i := 0; i < n; i++ {
+		select {
+		case elc.S <- T(i):
+		case <-time.After(10 * time.Millisecond):
+			return i
+		}
+	}
+	elc.Stats()
+	return n
+}
+
+func TestGroupReject(t *testing.T) {
+	g := NewElasticGroup(100, false, Reject)
+	a, b := newUnbuffered(t, inGroup(g)), newUnbuffered(t, inGroup(g))
+	trySend(a, 80)
+	trySend(b, 50)
+	if s := b.Stats(); s.Len != 20 || s.Drops.Group != 30 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	if g.Used() != 100 {
+		t.Fatalf("Used %d", g.Used())
+	}
+	close(a.S)
+	for range a.R {
+	}
+	if g.Used() != 20 {
+		t.Fatalf("Used %d after close", g.Used())
+	}
+}
+
+func TestGroupDropLargest(t *testing.T) {
+	g := NewElasticGroup(100, false, DropLargest)
+	a, b := newUnbuffered(t, inGroup(g)), newUnbuffered(t, inGroup(g))
+	trySend(a, 80)
+	trySend(b, 50)
+	for i := 0; g.Used() != 100; i++ {
+		if i == 1000 {
+			t.Fatalf("Used %d", g.Used())
+		}
+		time.Sleep(1 * time.Millisecond)
+	}
+	sa, sb := a.Stats(), b.Stats()
+	if sa.Len != 50 || sa.Drops.Group != 30 || sb.Len != 50 {
+		t.Fatalf("Stats: %+v, %+v", sa, sb)
+	}
+	if v := <-a.R; v != 30 {
+		t.Fatalf("Got %d from a", v)
+	}
+}
+
+func TestGroupBlockLargest(t *testing.T) {
+	g := NewElasticGroup(100, false, BlockLargest)
+	a, b := newUnbuffered(t, inGroup(g)), newUnbuffered(t, inGroup(g))
+	if n := trySend(a, 80); n != 80 {
+		t.Fatalf("Sent %d to a", n)
+	}
+	if n := trySend(b, 50); n != 22 {
+		t.Fatalf("Sent %d to b", n)
+	}
+	if n := trySend(a, 1); n != 0 {
+		t.Fatalf("a not blocked")
+	}
+	<-a.R
+	<-a.R
+	if n := trySend(b, 1); n != 1 {
+		t.Fatalf("b not unblocked")
+	}
+	if s := b.Stats(); s.Drops.Blocked != 1 || s.Drops.Group != 0 {
+		t.Fatalf("Stats: %+v", s)
+	}
+}
+
+func TestGroupBytes(t *testing.T) {
+	g := NewElasticGroup(1000, true, Reject)
+	o := DefaultOptions[[]byte]()
+	o.Group = g
+	if _, err := NewElasticWithOptions(o); err != ErrGroup {
+		t.Fatalf("err = %v", err)
+	}
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.Sizer = func(b []byte) int { return len(b) }
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		elc.S <- make([]byte, 300)
+	}
+	if s := elc.Stats(); s.Len != 3 || s.Drops.Group != 2 || g.Used() != 900 {
+		t.Fatalf("Used %d, stats: %+v", g.Used(), s)
+	}
+}
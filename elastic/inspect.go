@@ -0,0 +1,133 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// Snapshot returns a copy of the items in the internal queue of the
+// elastic channel, in order. Items in the send-side and receive-side
+// buffers are not included. Snapshot returns nil if the channel
+// goroutine has exited.
+//
+// Snapshot does not modify the queue. Items spilled to disk are read
+// back from the segment files; items that cannot be read or decoded
+// are left out of the snapshot (but not out of the queue).
+//
+// Snapshot, like Purge, is serialized with the elastic channel
+// goroutine, and takes time proportional to the queue length (much
+// more if the channel spills to disk); it is meant for inspection,
+// not for the data path.
+func (e Elastic[E]) Snapshot() []E {
+	var vs []E
+	en := e.eng
+	en.do(func() {
+		vs = make([]E, 0, en.q.Len())
+		if en.sq != nil {
+			en.sq.scan(func(v E) { vs = append(vs, v) })
+			return
+		}
+		en.filter(func(v E) bool {
+			vs = append(vs, v)
+			return true
+		})
+	})
+	return vs
+}
+
+// Purge removes from the internal queue of the elastic channel the
+// items for which pred returns true, and returns their number. Items
+// in the send-side and receive-side buffers are not affected. Purged
+// items are not counted as drops, and are not handed to OnDrop. pred
+// is called from the elastic channel goroutine, and must not block.
+func (e Elastic[E]) Purge(pred func(v E) bool) int {
+	var n int
+	en := e.eng
+	en.do(func() {
+		n = en.filter(func(v E) bool { return !pred(v) })
+		en.shrink()
+		if en.marks != nil {
+			en.mark()
+		}
+		if en.gm != nil {
+			en.gSync()
+		}
+	})
+	return n
+}
+
+// PushFront adds v to the front of the internal queue of the elastic
+// channel, so that it is delivered before any item in the queue (but
+// after the items already in the receive-side buffer). It returns
+// false, without adding v, if the queue is full (regardless of the
+// Overflow policy), if v does not fit in the group budget, or if the
+// channel goroutine has exited.
+func (e Elastic[E]) PushFront(v E) bool {
+	var ok bool
+	en := e.eng
+	en.do(func() { ok = en.pushFront(v) })
+	return ok
+}
+
+// pushFront adds v to the front of the queue, if it fits.
+func (en *engine[E]) pushFront(v E) bool {
+	sz := en.sizeOf(v)
+	if !en.fits(sz) || en.gReject(sz) {
+		return false
+	}
+	c := en.q.Cap()
+	if !en.q.PushFront(v) {
+		return false
+	}
+	en.st.In++
+	if en.q.Cap() != c {
+		en.st.Grows++
+	}
+	if en.bytes += sz; en.bytes > en.st.PeakBytes {
+		en.st.PeakBytes = en.bytes
+	}
+	if l := en.q.Len(); l > en.st.MaxLen {
+		en.st.MaxLen = l
+	}
+	if en.marks != nil {
+		en.mark()
+	}
+	if en.gm != nil {
+		en.gSync()
+	}
+	return true
+}
+
+// filter calls keep with every item in the queue, in order, and
+// removes the items for which keep returns false. Returns the number
+// of items removed. Kept items keep their enqueue times (TTL).
+func (en *engine[E]) filter(keep func(v E) bool) int {
+	n := 0
+	for i := en.q.Len(); i > 0; i-- {
+		var t int64
+		if en.tq != nil {
+			t, _ = en.tq.front()
+		}
+		v, ok := en.q.PopFront()
+		if !ok {
+			// Spill I/O error; the rest are lost.
+			break
+		}
+		if !keep(v) {
+			en.bytes -= en.sizeOf(v)
+			n++
+			continue
+		}
+		if en.tq != nil {
+			ok = en.tq.pushBackAt(v, t)
+		} else {
+			ok = en.q.PushBack(v)
+		}
+		if !ok {
+			en.bytes -= en.sizeOf(v)
+			en.st.Drops.Failed++
+			en.drop(v)
+		}
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.MaxQueue = 100
+	o.TTL = time.Hour
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		elc.S <- T(i)
+	}
+	front := func() (ts int64) {
+		elc.eng.view(func() { ts, _ = elc.eng.tq.ts.PeekFront() })
+		return ts
+	}
+	ts0 := front()
+	vs := elc.Snapshot()
+	if len(vs) != 100 || vs[0] != 0 || vs[99] != 99 {
+		t.Fatalf("Snapshot: %v", vs)
+	}
+	if n := elc.Purge(func(v T) bool { return v%2 == 1 }); n != 50 {
+		t.Fatalf("Purged %d", n)
+	}
+	if ts := front(); ts != ts0 {
+		t.Fatalf("Timestamp changed: %d != %d", ts, ts0)
+	}
+	if !elc.PushFront(-1) {
+		t.Fatal("PushFront failed")
+	}
+	s := elc.Stats()
+	if s.Len != 51 || s.In != 101 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	close(elc.S)
+	exp := T(-1)
+	for v := range elc.R {
+		if v != exp {
+			t.Fatalf("Received %d != %d", v, exp)
+		}
+		exp += 2
+		if exp == 1 {
+			exp = 0
+		}
+	}
+	if exp != 100 {
+		t.Fatalf("Received up to %d", exp)
+	}
+	if elc.PushFront(0) || elc.Snapshot() != nil {
+		t.Fatal("Inspection after exit")
+	}
+}
+
+func TestPushFrontFull(t *testing.T) {
+	o := DefaultOptions[T]()
+	o.ReceiveBuffer = 0
+	o.MaxQueue = 10
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if !elc.PushFront(T(i)) {
+			t.Fatalf("PushFront %d failed", i)
+		}
+	}
+	if elc.PushFront(10) {
+		t.Fatal("PushFront on full queue")
+	}
+	if v := <-elc.R; v != 9 {
+		t.Fatalf("Received %d", v)
+	}
+}
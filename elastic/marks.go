@@ -0,0 +1,56 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// Mark is a watermark notification.
+type Mark int
+
+const (
+	// Mark values.
+	MarkLow  Mark = iota // Queue length fell to LowMark.
+	MarkHigh             // Queue length reached HighMark.
+)
+
+// Marks returns the channel where watermark notifications are sent
+// (see Options.HighMark), or nil if the elastic channel has no
+// watermarks. Notifications never block the elastic channel
+// goroutine: the channel holds only the latest notification, and
+// older ones are discarded if not received in time.
+//
+// Producers can use Marks to throttle themselves, like:
+//
+//	select {
+//	case m := <-elc.Marks():
+//		throttle = m == MarkHigh
+//	...
+//	}
+func (e Elastic[E]) Marks() <-chan Mark {
+	return e.eng.marks
+}
+
+// mark sends a watermark notification, if the queue length has
+// crossed a watermark. It must only be called if there are watermarks
+// (en.marks != nil).
+func (en *engine[E]) mark() {
+	l := en.q.Len()
+	if !en.st.High && l >= en.o.HighMark {
+		en.st.High = true
+		en.notify(MarkHigh)
+	} else if en.st.High && l <= en.o.LowMark {
+		en.st.High = false
+		en.notify(MarkLow)
+	}
+}
+
+// notify sends notification m, replacing any notification not yet
+// received.
+func (en *engine[E]) notify(m Mark) {
+	select {
+	case <-en.marks:
+	default:
+	}
+	en.marks <- m
+}
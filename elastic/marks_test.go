@@ -0,0 +1,72 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+func expectMark(t *testing.T, elc ElasticT, m Mark) {
+	t.Helper()
+	select {
+	case m1 := <-elc.Marks():
+		if m1 != m {
+			t.Fatalf("Got mark %d != %d", m1, m)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatalf("No mark %d", m)
+	}
+}
+
+func expectNoMark(t *testing.T, elc ElasticT) {
+	t.Helper()
+	select {
+	case m := <-elc.Marks():
+		t.Fatalf("Unexpected mark %d", m)
+	default:
+	}
+}
+
+func TestMarks(t *testing.T) {
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.HighMark, o.LowMark = 100, 10
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 99; i++ {
+		elc.S <- T(i)
+	}
+	expectNoMark(t, elc)
+	elc.S <- 99
+	expectMark(t, elc, MarkHigh)
+	if s := elc.Stats(); !s.High {
+		t.Fatalf("Stats: %+v", s)
+	}
+	for i := 0; i < 89; i++ {
+		<-elc.R
+	}
+	expectNoMark(t, elc)
+	<-elc.R
+	expectMark(t, elc, MarkLow)
+	// Unreceived notifications are replaced by newer ones.
+	for i := 0; i < 90; i++ {
+		elc.S <- T(i)
+	}
+	for i := 0; i < 90; i++ {
+		<-elc.R
+	}
+	expectMark(t, elc, MarkLow)
+	expectNoMark(t, elc)
+}
+
+func TestMarksCheck(t *testing.T) {
+	o := DefaultOptions[T]()
+	o.HighMark, o.LowMark = 10, 10
+	if _, err := NewElasticWithOptions(o); err != ErrMarks {
+		t.Fatalf("err = %v", err)
+	}
+	if m := NewElasticT().Marks(); m != nil {
+		t.Fatal("Marks not nil")
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Options specifies the parameters of an elastic channel. Start from
+// the values returned by DefaultOptions and change the ones you need.
+type Options[E any] struct {
+	// Shrink mode of the internal queue. Ignored if Shrinker is
+	// not nil.
+	Mode ShrinkMode
+	// If not nil, the policy that decides when the internal queue
+	// is shrinked. A policy value with state (like *ShrinkIdle)
+	// must not be shared by many channels.
+	Shrinker ShrinkPolicy
+	// Fixed buffer sizes at the send and the receive sides of the
+	// elastic channel. Zero gives unbuffered channels.
+	SendBuffer    int
+	ReceiveBuffer int
+	// Initial size of the internal queue. Must be a power of 2.
+	// The queue is never shrinked below this size.
+	InitQueue int
+	// Maximum length of the internal queue (at most maxQSz), and
+	// what to do when it is reached.
+	MaxQueue int
+	Overflow Overflow
+	// If not nil, called with every item dropped due to
+	// overflow. It is called from the elastic channel goroutine
+	// and must not block.
+	OnDrop func(E)
+	// If not nil, Sizer returns the size of an item in bytes; it
+	// must return the same size every time it is called for the
+	// same item. The channel then keeps track of the bytes in its
+	// internal queue (see Stats), and, if MaxBytes is not zero,
+	// limits them to MaxBytes, in addition to MaxQueue items. With
+	// the Block policy, the last item received may exceed
+	// MaxBytes. Sizer cannot be used with Spill.
+	Sizer    func(E) int
+	MaxBytes int
+	// If not nil, the channel joins Group, and its internal queue
+	// counts against the group's budget. If the group counts
+	// bytes, Sizer must be set.
+	Group *ElasticGroup
+	// Watermarks. If HighMark is not zero, a MarkHigh
+	// notification is sent when the length of the internal queue
+	// reaches HighMark, and a MarkLow notification when it then
+	// falls to LowMark or below (see Elastic.Marks). LowMark must
+	// be less than HighMark.
+	HighMark int
+	LowMark  int
+	// Number of items to receive from the input channel
+	// consecutively (batch size). Must be at least 1.
+	MaxReceive int
+	// If not zero, the internal queue is stored in a linked list of
+	// chunks of this many items, instead of a single slice that
+	// doubles in size. A chunked queue grows and shrinks one chunk
+	// at a time, without copying its items. InitQueue is ignored,
+	// drained chunks are released immediately, except for one
+	// spare chunk which is released when the shrink policy says
+	// so. Chunk cannot be used with Spill.
+	Chunk int
+	// If not zero, items that have been in the internal queue for
+	// longer than TTL are dropped instead of being delivered. If
+	// OnExpire is not nil, it is called with every expired item,
+	// from the elastic channel goroutine; it must not block. Items
+	// already in the receive-side buffer are not checked; use
+	// ReceiveBuffer 0 for strict expiry. TTL cannot be used with
+	// Spill.
+	TTL      time.Duration
+	OnExpire func(E)
+	// If not nil, the channel is canceled (see Elastic.Cancel)
+	// when Context is done. Undelivered items are discarded.
+	Context context.Context
+	// If not nil, items beyond Spill.Threshold are spilled to
+	// disk. MaxQueue still limits the total number of items.
+	Spill *Spill[E]
+}
+
+// DefaultOptions returns the options used by NewElastic: practically
+// unlimited queue, shrink whenever possible.
+func DefaultOptions[E any]() Options[E] {
+	return Options[E]{
+		Mode:          Shrink,
+		SendBuffer:    sendBuffer,
+		ReceiveBuffer: receiveBuffer,
+		InitQueue:     1,
+		MaxQueue:      maxQSz,
+		Overflow:      Block,
+		MaxReceive:    maxReceive,
+	}
+}
+
+// Errors returned by NewElasticWithOptions.
+var (
+	ErrMode       = errors.New("elastic: invalid shrink mode")
+	ErrShrinker   = errors.New("elastic: invalid shrink policy")
+	ErrBuffer     = errors.New("elastic: invalid buffer size")
+	ErrInitQueue  = errors.New("elastic: invalid initial queue size")
+	ErrMaxQueue   = errors.New("elastic: invalid max queue length")
+	ErrOverflow   = errors.New("elastic: invalid overflow policy")
+	ErrMaxReceive = errors.New("elastic: invalid receive batch size")
+	ErrChunk      = errors.New("elastic: invalid chunk size")
+	ErrMarks      = errors.New("elastic: invalid watermarks")
+	ErrTTL        = errors.New("elastic: invalid TTL")
+	ErrSizer      = errors.New("elastic: invalid sizer or byte limit")
+)
+
+// check validates the options.
+func (o *Options[E]) check() error {
+	if o.Shrinker != nil {
+		if c, ok := o.Shrinker.(interface{ check() error }); ok {
+			if err := c.check(); err != nil {
+				return err
+			}
+		}
+	} else if o.Mode < Shrink || o.Mode > NoShrink {
+		return ErrMode
+	}
+	if o.SendBuffer < 0 || o.ReceiveBuffer < 0 {
+		return ErrBuffer
+	}
+	if o.InitQueue <= 0 || o.InitQueue > maxQSz ||
+		o.InitQueue&(o.InitQueue-1) != 0 {
+		return ErrInitQueue
+	}
+	if o.MaxQueue <= 0 || o.MaxQueue > maxQSz {
+		return ErrMaxQueue
+	}
+	if o.Overflow < Block || o.Overflow > DropOldest {
+		return ErrOverflow
+	}
+	if o.MaxReceive <= 0 {
+		return ErrMaxReceive
+	}
+	if o.HighMark < 0 ||
+		o.HighMark > 0 && (o.LowMark < 0 || o.LowMark >= o.HighMark) {
+		return ErrMarks
+	}
+	if o.MaxBytes < 0 || o.MaxBytes > 0 && o.Sizer == nil ||
+		o.Sizer != nil && o.Spill != nil {
+		return ErrSizer
+	}
+	if o.Group != nil && o.Group.bytes && o.Sizer == nil {
+		return ErrGroup
+	}
+	if o.TTL < 0 || o.TTL > 0 && o.Spill != nil {
+		return ErrTTL
+	}
+	if o.Chunk < 0 || o.Chunk > 0 && o.Spill != nil {
+		return ErrChunk
+	}
+	if o.Spill != nil {
+		return o.Spill.check()
+	}
+	return nil
+}
+
+// NewElasticWithOptions creates and returns a new elastic channel
+// with the parameters specified by o. Returns an error if o is
+// invalid, or if the spill directory cannot be created.
+func NewElasticWithOptions[E any](o Options[E]) (Elastic[E], error) {
+	if err := o.check(); err != nil {
+		return Elastic[E]{}, err
+	}
+	en, err := newEngine(o)
+	if err != nil {
+		return Elastic[E]{}, err
+	}
+	return newElastic(en), nil
+}
+
+// mustElastic is like NewElasticWithOptions, but panics if o is
+// invalid.
+func mustElastic[E any](o Options[E]) Elastic[E] {
+	e, err := NewElasticWithOptions(o)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
@@ -0,0 +1,82 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOptionsCheck(t *testing.T) {
+	bad := []struct {
+		f   func(o *Options[T])
+		err error
+	}{
+		{func(o *Options[T]) { o.Mode = -1 }, ErrMode},
+		{func(o *Options[T]) { o.SendBuffer = -1 }, ErrBuffer},
+		{func(o *Options[T]) { o.ReceiveBuffer = -1 }, ErrBuffer},
+		{func(o *Options[T]) { o.InitQueue = 0 }, ErrInitQueue},
+		{func(o *Options[T]) { o.InitQueue = 3 }, ErrInitQueue},
+		{func(o *Options[T]) { o.MaxQueue = 0 }, ErrMaxQueue},
+		{func(o *Options[T]) { o.MaxQueue = maxQSz + 1 }, ErrMaxQueue},
+		{func(o *Options[T]) { o.Overflow = 3 }, ErrOverflow},
+		{func(o *Options[T]) { o.MaxReceive = 0 }, ErrMaxReceive},
+	}
+	for i, b := range bad {
+		o := DefaultOptions[T]()
+		b.f(&o)
+		if _, err := NewElasticWithOptions(o); err != b.err {
+			t.Errorf("%d: err = %v != %v", i, err, b.err)
+		}
+	}
+	if _, err := NewElasticWithOptions(Options[T]{}); err == nil {
+		t.Error("Zero options accepted")
+	}
+}
+
+func TestOptionsUnbuffered(t *testing.T) {
+	const M = 10
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.InitQueue, o.MaxQueue, o.MaxReceive = 4, M, 1
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < M; i++ {
+		elc.S <- T(i)
+	}
+	select {
+	case elc.S <- M:
+		t.Fatal("Not blocked when full")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(elc.S)
+	if r := recvAll(t, elc.R); len(r) != M {
+		t.Fatalf("Received %d items", len(r))
+	}
+}
+
+func benchConOpt(b *testing.B, o Options[T]) {
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		b.Fatal(err)
+	}
+	end := make(chan int)
+	b.ResetTimer()
+	go produce(b.N, elc.S, end)
+	go consume(b.N, elc.R, end)
+	<-end
+	<-end
+	b.StopTimer()
+}
+
+func BenchmarkConOptUnbuffered(b *testing.B) {
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer, o.MaxReceive = 0, 0, 1
+	benchConOpt(b, o)
+}
+
+func BenchmarkConOptBulk(b *testing.B) {
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer, o.InitQueue = 4096, 4096, 1024
+	benchConOpt(b, o)
+}
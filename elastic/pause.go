@@ -0,0 +1,26 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// Pause stops the elastic channel from delivering items to R, until
+// Resume is called. While paused, the channel keeps accepting items
+// from S, and its queue grows as required (subject to MaxQueue,
+// MaxBytes and the Overflow policy). Items already in the
+// receive-side buffer can still be received; set ReceiveBuffer to 0
+// to stop delivery at once. If S is closed while the channel is
+// paused, R is closed only after the channel is resumed and its queue
+// is drained. Pausing a paused channel has no effect.
+func (e Elastic[E]) Pause() {
+	en := e.eng
+	en.do(func() { en.st.Paused = true })
+}
+
+// Resume resumes delivery of items to R, after Pause. Resuming a
+// channel that is not paused has no effect.
+func (e Elastic[E]) Resume() {
+	en := e.eng
+	en.do(func() { en.st.Paused = false })
+}
@@ -0,0 +1,39 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPause(t *testing.T) {
+	const N = 100
+	elc := newUnbuffered[T](t, nil)
+	elc.Pause()
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	select {
+	case v := <-elc.R:
+		t.Fatalf("Received %d while paused", v)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if s := elc.Stats(); !s.Paused || s.Len != N || s.Out != 0 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	close(elc.S)
+	elc.Resume()
+	var buf [8]T
+	if n := elc.RecvBatch(buf[:]); n != len(buf) || buf[7] != 7 {
+		t.Fatalf("RecvBatch %d: %v", n, buf)
+	}
+	i := len(buf)
+	for v := range elc.R {
+		if v != T(i) {
+			t.Fatalf("Received %d != %d", v, i)
+		}
+		i++
+	}
+	if i != N || elc.Stats().Paused {
+		t.Fatalf("Received %d items, stats: %+v", i, elc.Stats())
+	}
+}
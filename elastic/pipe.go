@@ -0,0 +1,227 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import (
+	"errors"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+// Pipe is an elastic, in-memory, byte pipe. Unlike io.Pipe, writes do
+// not wait for the reader: written bytes are kept in a ring buffer
+// that grows as required, and shrinks according to the pipe's shrink
+// mode. Optionally, the buffer can be capped; writes then either
+// block, or fail, while the buffer is full.
+//
+// Pipe implements io.ReadWriteCloser; Close closes the write side,
+// and CloseRead the read side. It is safe to call Read and Write
+// concurrently, from any number of goroutines. A Pipe must be
+// created with NewPipe, and must not be copied after first use.
+type Pipe struct {
+	mu     sync.Mutex
+	c      sync.Cond // Signaled when bytes are read or written, or on close.
+	b      []byte    // Ring buffer (power-of-2 size).
+	r      int       // Read offset in b.
+	n      int       // Bytes in b.
+	mode   ShrinkMode
+	max    int  // Max bytes in b, or 0.
+	block  bool // Block writes when full.
+	closed bool
+	err    error // Error returned by Read once drained.
+	rerr   error // Error returned by Write once the read side is closed.
+}
+
+// Initial (and minimum) pipe buffer size.
+const pipeMin = 512
+
+// ErrPipeFull is returned by Pipe.Write, if the pipe is capped, is
+// not blocking, and is full.
+var ErrPipeFull = errors.New("elastic: pipe full")
+
+// NewPipe creates and returns a new elastic pipe, using the specified
+// shrink mode. If max > 0, the pipe buffers up to max bytes. Then,
+// if block is true, writes block until the reader makes room for
+// them; otherwise they write as many bytes as they can and return
+// ErrPipeFull.
+func NewPipe(mode ShrinkMode, max int, block bool) *Pipe {
+	if max < 0 {
+		panic("Invalid pipe max size")
+	}
+	p := &Pipe{
+		b:     make([]byte, pipeMin),
+		mode:  mode,
+		max:   max,
+		block: block,
+	}
+	p.c.L = &p.mu
+	return p
+}
+
+// Len returns the number of bytes buffered in the pipe.
+func (p *Pipe) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.n
+}
+
+// Write writes the bytes in b to the pipe. It returns
+// io.ErrClosedPipe if the pipe has been closed, the error given to
+// CloseRead if the read side has been closed, and ErrPipeFull if not
+// all bytes fit (see NewPipe).
+func (p *Pipe) Write(b []byte) (n int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		if p.rerr != nil {
+			return n, p.rerr
+		}
+		if p.closed {
+			return n, io.ErrClosedPipe
+		}
+		if len(b) == 0 {
+			return n, nil
+		}
+		m := len(b)
+		if p.max > 0 {
+			m = min(m, p.max-p.n)
+		}
+		if m == 0 {
+			if !p.block {
+				return n, ErrPipeFull
+			}
+			p.c.Wait()
+			continue
+		}
+		p.write(b[:m])
+		n += m
+		b = b[m:]
+		p.c.Broadcast()
+	}
+}
+
+// Read reads up to len(b) bytes from the pipe, blocking until at
+// least one byte is available. Once the pipe is closed and drained,
+// Read returns io.EOF, or the error given to CloseWithError. If len(b)
+// is zero, Read returns 0, nil at once. Once the read side is closed,
+// Read returns io.ErrClosedPipe.
+func (p *Pipe) Read(b []byte) (n int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rerr != nil {
+		return 0, io.ErrClosedPipe
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	for p.n == 0 {
+		if p.closed {
+			return 0, p.err
+		}
+		p.c.Wait()
+	}
+	n = p.read(b)
+	p.c.Broadcast()
+	return n, nil
+}
+
+// Close closes the write side of the pipe. It is equivalent to
+// CloseWithError(nil).
+func (p *Pipe) Close() error {
+	return p.CloseWithError(nil)
+}
+
+// CloseWithError closes the write side of the pipe. Bytes already
+// written can still be read; after that, Read returns err, or io.EOF
+// if err is nil. Writes, including blocked ones, return
+// io.ErrClosedPipe. Closing a closed pipe has no effect.
+func (p *Pipe) CloseWithError(err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	p.closed, p.err = true, err
+	p.c.Broadcast()
+	return nil
+}
+
+// CloseRead closes the read side of the pipe, and discards the bytes
+// buffered in it. Writes, including blocked ones, then return err, or
+// io.ErrClosedPipe if err is nil; Reads return io.ErrClosedPipe.
+// Closing a closed read side has no effect.
+func (p *Pipe) CloseRead(err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rerr != nil {
+		return nil
+	}
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	p.rerr = err
+	p.b, p.r, p.n = make([]byte, pipeMin), 0, 0
+	p.c.Broadcast()
+	return nil
+}
+
+// write appends b to the ring, growing it if required.
+func (p *Pipe) write(b []byte) {
+	if p.n+len(b) > len(p.b) {
+		p.resize(pipeSize(p.n + len(b)))
+	}
+	w := (p.r + p.n) & (len(p.b) - 1)
+	k := copy(p.b[w:], b)
+	copy(p.b, b[k:])
+	p.n += len(b)
+}
+
+// read moves up to len(b) bytes from the ring to b, and shrinks the
+// ring if required by the shrink mode. Returns the number of bytes
+// moved.
+func (p *Pipe) read(b []byte) int {
+	m := min(len(b), p.n)
+	k := copy(b[:m], p.b[p.r:])
+	copy(b[k:m], p.b)
+	p.r = (p.r + m) & (len(p.b) - 1)
+	p.n -= m
+	if p.n == 0 {
+		p.r = 0
+	}
+	if len(p.b) > pipeMin && mustShrink(p.mode, p.n, len(p.b)) {
+		p.resize(pipeSize(p.n))
+	}
+	return m
+}
+
+// pipeSize returns the ring size required for n bytes: n rounded up
+// to a power of 2, but not below pipeMin. It panics if the size
+// overflows an int.
+func pipeSize(n int) int {
+	if n < 0 {
+		panic("Pipe too large")
+	}
+	if n <= pipeMin {
+		return pipeMin
+	}
+	sz := 1 << bits.Len(uint(n-1))
+	if sz < n {
+		panic("Pipe too large")
+	}
+	return sz
+}
+
+// resize moves the bytes in the ring to a new buffer of size sz.
+func (p *Pipe) resize(sz int) {
+	b := make([]byte, sz)
+	k := copy(b[:p.n], p.b[p.r:])
+	copy(b[k:p.n], p.b)
+	p.b, p.r = b, 0
+}
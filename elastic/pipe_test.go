@@ -0,0 +1,159 @@
+package elastic
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	data := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(data)
+	p := NewPipe(Shrink, 0, false)
+	for i := 0; i < len(data); i += 1000 {
+		if n, err := p.Write(data[i : i+1000]); n != 1000 || err != nil {
+			t.Fatalf("Write %d, %v", n, err)
+		}
+	}
+	if p.Len() != len(data) {
+		t.Fatalf("Len %d", p.Len())
+	}
+	p.Close()
+	if _, err := p.Write(data[:1]); err != io.ErrClosedPipe {
+		t.Fatalf("Write on closed pipe: %v", err)
+	}
+	b, err := io.ReadAll(p)
+	if err != nil || !bytes.Equal(b, data) {
+		t.Fatalf("ReadAll %d bytes, %v", len(b), err)
+	}
+	if len(p.b) != pipeMin {
+		t.Fatalf("Buffer size %d", len(p.b))
+	}
+}
+
+func TestPipeCap(t *testing.T) {
+	p := NewPipe(NoShrink, 10, false)
+	if n, err := p.Write(make([]byte, 15)); n != 10 || err != ErrPipeFull {
+		t.Fatalf("Write %d, %v", n, err)
+	}
+	p = NewPipe(NoShrink, 10, true)
+	done := make(chan error)
+	go func() {
+		_, err := p.Write(make([]byte, 15))
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("Write not blocked")
+	case <-time.After(10 * time.Millisecond):
+	}
+	buf := make([]byte, 8)
+	if n, _ := p.Read(buf); n != 8 {
+		t.Fatalf("Read %d", n)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	go func() {
+		_, err := p.Write(make([]byte, 10))
+		done <- err
+	}()
+	time.Sleep(1 * time.Millisecond)
+	errX := errors.New("X")
+	p.CloseWithError(errX)
+	if err := <-done; err != io.ErrClosedPipe {
+		t.Fatalf("Write: %v", err)
+	}
+	if b, err := io.ReadAll(p); len(b) != 10 || err != errX {
+		t.Fatalf("ReadAll %d bytes, %v", len(b), err)
+	}
+}
+
+func TestPipeReadEmpty(t *testing.T) {
+	p := NewPipe(Shrink, 0, false)
+	done := make(chan struct{})
+	go func() {
+		if n, err := p.Read(nil); n != 0 || err != nil {
+			t.Errorf("Read %d, %v", n, err)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Read of 0 bytes blocked")
+	}
+}
+
+func TestPipeCloseRead(t *testing.T) {
+	p := NewPipe(NoShrink, 10, true)
+	done := make(chan error)
+	go func() {
+		_, err := p.Write(make([]byte, 15))
+		done <- err
+	}()
+	time.Sleep(1 * time.Millisecond)
+	errX := errors.New("X")
+	p.CloseRead(errX)
+	if err := <-done; err != errX {
+		t.Fatalf("Blocked Write: %v", err)
+	}
+	if _, err := p.Write(make([]byte, 1)); err != errX {
+		t.Fatalf("Write: %v", err)
+	}
+	if n, err := p.Read(make([]byte, 1)); n != 0 || err != io.ErrClosedPipe {
+		t.Fatalf("Read %d, %v", n, err)
+	}
+	if p.Len() != 0 {
+		t.Fatalf("Len %d", p.Len())
+	}
+	p = NewPipe(Shrink, 0, false)
+	p.CloseRead(nil)
+	if _, err := p.Write(make([]byte, 1)); err != io.ErrClosedPipe {
+		t.Fatalf("Write: %v", err)
+	}
+}
+
+func TestPipeSize(t *testing.T) {
+	for _, c := range []struct{ n, sz int }{
+		{0, pipeMin}, {pipeMin, pipeMin}, {pipeMin + 1, 2 * pipeMin},
+		{1<<20 + 1, 1 << 21}, {math.MaxInt >> 1, 1 << (bits.UintSize - 2)},
+	} {
+		if sz := pipeSize(c.n); sz != c.sz {
+			t.Fatalf("pipeSize(%d) = %d != %d", c.n, sz, c.sz)
+		}
+	}
+	for _, n := range []int{math.MaxInt>>1 + 2, math.MaxInt, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("pipeSize(%d) did not panic", n)
+				}
+			}()
+			pipeSize(n)
+		}()
+	}
+}
+
+func TestPipeData(t *testing.T) {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(2)).Read(data)
+	for _, mode := range []ShrinkMode{Shrink, ShrinkEmpty, NoShrink} {
+		p := NewPipe(mode, 4096, true)
+		go func() {
+			for i := 0; i < len(data); i += 333 {
+				p.Write(data[i:min(i+333, len(data))])
+			}
+			p.Close()
+		}()
+		b, err := io.ReadAll(p)
+		if err != nil || !bytes.Equal(b, data) {
+			t.Fatalf("Mode %d: ReadAll %d bytes, %v", mode, len(b), err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// Priority is an elastic channel of E-typed elements with multiple
+// priority classes. Items are sent using Send, and received from R.
+// Items of a higher priority class are always delivered before items
+// of lower classes; within each class items are delivered in FIFO
+// order.
+//
+// The receive side of a Priority channel is unbuffered, so that a
+// high priority item does not wait behind items already handed to
+// the receive-side buffer.
+type Priority[E any] struct {
+	R   <-chan E // Receive direction.
+	cin chan<- prioItem[E]
+	n   int
+}
+
+// prioItem is an item sent to a Priority channel.
+type prioItem[E any] struct {
+	p int
+	v E
+}
+
+// NewPriority creates and returns a new priority elastic channel with
+// n priority classes (0 to n-1, 0 is the highest priority), using the
+// specified shrink mode. Every class has its own, practically
+// unlimited, queue.
+func NewPriority[E any](n int, mode ShrinkMode) Priority[E] {
+	if n <= 0 {
+		panic("Invalid number of priority classes")
+	}
+	cin := make(chan prioItem[E], sendBuffer)
+	cout := make(chan E)
+	qs := make([]*cQ[E], n)
+	for i := range qs {
+		qs[i] = newCQ[E](1, maxQSz)
+	}
+	go prioRun(mode, qs, cout, cin)
+	return Priority[E]{R: cout, cin: cin, n: n}
+}
+
+// Send sends v with priority prio. It panics if prio is not a valid
+// priority class, or if the channel has been closed.
+func (p Priority[E]) Send(prio int, v E) {
+	if prio < 0 || prio >= p.n {
+		panic("Invalid priority")
+	}
+	p.cin <- prioItem[E]{p: prio, v: v}
+}
+
+// Close closes the send direction of the channel. R is closed once
+// all items have been delivered.
+func (p Priority[E]) Close() {
+	close(p.cin)
+}
+
+// prioRun runs as the priority elastic channel goroutine. Like
+// engine.run, it tries to flush the input channel before returning
+// back to the select statement.
+func prioRun[E any](mode ShrinkMode,
+	qs []*cQ[E], cout chan<- E, cin <-chan prioItem[E]) {
+
+	var in <-chan prioItem[E]
+	var out chan<- E
+	var vi prioItem[E]
+	var vo E
+	var ok bool
+
+	in = cin
+	for {
+		// Find the highest priority class with items queued.
+		var q *cQ[E]
+		out = nil
+		for _, q = range qs {
+			if vo, ok = q.PeekFront(); ok {
+				out = cout
+				break
+			}
+		}
+		if out == nil && in == nil {
+			close(cout)
+			return
+		}
+		select {
+		case vi, ok = <-in:
+		inLoop:
+			for i := 1; ; i++ {
+				if !ok {
+					in = nil
+					break
+				}
+				qs[vi.p].PushBack(vi.v)
+				if i == maxReceive {
+					break
+				}
+				select {
+				case vi, ok = <-in:
+				default:
+					break inLoop
+				}
+			}
+		case out <- vo:
+			q.PopFront()
+			if mustShrink(mode, q.Len(), q.Cap()) {
+				q.Compact(1)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	const N, P = 3000, 3
+	p := NewPriority[T](P, Shrink)
+	for i := 0; i < N; i++ {
+		p.Send(i%P, T(i))
+	}
+	for len(p.cin) != 0 {
+		time.Sleep(1 * time.Millisecond)
+	}
+	p.Close()
+	i := 0
+	for v := range p.R {
+		exp := T(i%(N/P)*P + i/(N/P))
+		if v != exp {
+			t.Fatalf("Got %d != %d", v, exp)
+		}
+		i++
+	}
+	if i != N {
+		t.Fatalf("Received %d", i)
+	}
+}
+
+func TestPriorityConcurrent(t *testing.T) {
+	const N = 8192
+	p := NewPriority[T](2, NoShrink)
+	go func() {
+		for i := 0; i < N; i++ {
+			p.Send(1, T(i))
+		}
+		p.Close()
+	}()
+	if r := recvAll(t, p.R); len(r) != N {
+		t.Fatalf("Received %d", len(r))
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// NewRing creates and returns a new elastic channel whose queue is a
+// fixed-size ring of n items. When the ring is full, a new item
+// overwrites the oldest one, so senders never block, and receivers
+// always get the most recent n items (plus, at most, the items in the
+// send-side buffer, which are newer). The ring is allocated once, and
+// never grows or shrinks. The receive side is unbuffered, so that no
+// stale items are held outside the ring.
+//
+// Use Overwritten to get the number of items overwritten.
+func NewRing[E any](n int) Elastic[E] {
+	if n <= 0 || n > maxQSz {
+		panic("Invalid ring size")
+	}
+	o := DefaultOptions[E]()
+	o.Mode = NoShrink
+	o.ReceiveBuffer = 0
+	o.InitQueue = int(roundUp2(uint32(n)))
+	o.MaxQueue = n
+	o.Overflow = DropOldest
+	return mustElastic(o)
+}
+
+// Overwritten returns the number of items overwritten in a ring
+// channel (see NewRing); that is, the number of items dropped by the
+// DropOldest policy.
+func (e Elastic[E]) Overwritten() uint64 {
+	return e.Stats().Drops.Oldest
+}
@@ -0,0 +1,32 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	const N = 10
+	elc := NewRing[T](N)
+	for i := 0; i < 1000; i++ {
+		elc.S <- T(i)
+	}
+	for elc.Stats().In != 1000 {
+		time.Sleep(1 * time.Millisecond)
+	}
+	close(elc.S)
+	i := 1000 - N
+	for v := range elc.R {
+		if v != T(i) {
+			t.Fatalf("Received %d != %d", v, i)
+		}
+		i++
+	}
+	if i != 1000 {
+		t.Fatalf("Received up to %d", i)
+	}
+	s := elc.Stats()
+	if elc.Overwritten() != 1000-N || s.Cap != 16 || s.Grows != 0 {
+		t.Fatalf("Stats: %+v", s)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import "time"
+
+// ShrinkPolicy decides when, and how much, the internal queue of an
+// elastic channel is shrinked. ShrinkMode values are ShrinkPolicies,
+// and so are ShrinkFloor and *ShrinkIdle.
+type ShrinkPolicy interface {
+	// Shrink is called from the elastic channel goroutine every
+	// time an item is removed from the queue, with the length (l)
+	// and the capacity (c) of the queue. It returns the size to
+	// compact the queue to (the queue is never compacted below
+	// the size required to hold its items), or 0 to leave the
+	// queue alone. If after is not zero, Shrink will be called
+	// again after this time, even if no items are removed.
+	Shrink(l, c int) (sz int, after time.Duration)
+}
+
+// mustShrink tests if a queue with l elements and capacity c must be
+// compacted, according to the shrink mode.
+func mustShrink(mode ShrinkMode, l, c int) bool {
+	switch mode {
+	case Shrink:
+		return l < c>>1
+	case ShrinkEmpty:
+		return l == 0
+	}
+	return false
+}
+
+// Shrink implements ShrinkPolicy. It compacts the queue to the
+// smallest possible size, when required by the mode.
+func (mode ShrinkMode) Shrink(l, c int) (sz int, after time.Duration) {
+	if mustShrink(mode, l, c) {
+		return 1, 0
+	}
+	return 0, 0
+}
+
+// ShrinkFloor is a ShrinkPolicy that shrinks the queue as dictated by
+// Mode, but never below Floor elements.
+type ShrinkFloor struct {
+	Mode  ShrinkMode
+	Floor int
+}
+
+func (sf ShrinkFloor) check() error {
+	if sf.Mode < Shrink || sf.Mode > NoShrink ||
+		sf.Floor < 0 || sf.Floor > maxQSz {
+		return ErrShrinker
+	}
+	return nil
+}
+
+// Shrink implements ShrinkPolicy.
+func (sf ShrinkFloor) Shrink(l, c int) (sz int, after time.Duration) {
+	if c <= sf.Floor || !mustShrink(sf.Mode, l, c) {
+		return 0, 0
+	}
+	// Size 0 would leave the queue alone.
+	return max(sf.Floor, 1), 0
+}
+
+// ShrinkIdle is a ShrinkPolicy with hysteresis. It shrinks the queue
+// only after its occupancy (length / capacity) stays below Low for at
+// least Idle time. The queue is then compacted to the smallest
+// possible size, but not below Floor elements. A ShrinkIdle keeps
+// state, so every channel needs its own.
+type ShrinkIdle struct {
+	Low   float64       // Low watermark, in (0, 1].
+	Idle  time.Duration // Time below Low before shrinking.
+	Floor int           // Min queue size.
+	below time.Time     // Since when below Low, or zero.
+}
+
+func (si *ShrinkIdle) check() error {
+	if si.Low <= 0 || si.Low > 1 || si.Idle < 0 ||
+		si.Floor < 0 || si.Floor > maxQSz {
+		return ErrShrinker
+	}
+	return nil
+}
+
+// Shrink implements ShrinkPolicy.
+func (si *ShrinkIdle) Shrink(l, c int) (sz int, after time.Duration) {
+	fit := int(roundUp2(uint32(l)))
+	if fit < si.Floor {
+		fit = si.Floor
+	}
+	if float64(l) >= si.Low*float64(c) || fit >= c {
+		si.below = time.Time{}
+		return 0, 0
+	}
+	now := time.Now()
+	if si.below.IsZero() {
+		si.below = now
+	}
+	if d := now.Sub(si.below); d < si.Idle {
+		return 0, si.Idle - d
+	}
+	si.below = time.Time{}
+	return fit, 0
+}
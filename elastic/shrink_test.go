@@ -0,0 +1,92 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+// newUnbuffered returns an elastic channel with unbuffered send and
+// receive sides, so that all items are held in the internal queue.
+// If set is not nil, it is called to adjust the other options.
+func newUnbuffered[E any](t *testing.T, set func(o *Options[E])) Elastic[E] {
+	t.Helper()
+	o := DefaultOptions[E]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	if set != nil {
+		set(&o)
+	}
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return elc
+}
+
+func TestShrinkFloor(t *testing.T) {
+	for _, c := range []struct{ floor, cap int }{{100, 128}, {0, 1}} {
+		elc := newUnbuffered(t, func(o *Options[T]) {
+			o.Shrinker = ShrinkFloor{Mode: Shrink, Floor: c.floor}
+		})
+		for i := 0; i < 1000; i++ {
+			elc.S <- T(i)
+		}
+		for i := 0; i < 1000; i++ {
+			<-elc.R
+		}
+		if s := elc.Stats(); s.Cap != c.cap {
+			t.Fatalf("Floor %d: stats: %+v", c.floor, s)
+		}
+	}
+}
+
+func TestShrinkIdle(t *testing.T) {
+	const idle = 100 * time.Millisecond
+	elc := newUnbuffered(t, func(o *Options[T]) {
+		o.Shrinker = &ShrinkIdle{Low: 0.25, Idle: idle, Floor: 16}
+	})
+	for i := 0; i < 1000; i++ {
+		elc.S <- T(i)
+	}
+	for i := 0; i < 990; i++ {
+		<-elc.R
+	}
+	if s := elc.Stats(); s.Cap != 1024 || s.Compacts != 0 {
+		t.Fatalf("Stats before idle: %+v", s)
+	}
+	time.Sleep(3 * idle)
+	if s := elc.Stats(); s.Cap != 16 || s.Compacts != 1 {
+		t.Fatalf("Stats after idle: %+v", s)
+	}
+}
+
+func TestShrinkIdlePolicy(t *testing.T) {
+	si := &ShrinkIdle{Low: 0.5, Idle: time.Hour}
+	if sz, after := si.Shrink(10, 16); sz != 0 || after != 0 {
+		t.Fatalf("Above low: %d %v", sz, after)
+	}
+	if sz, after := si.Shrink(3, 16); sz != 0 || after != time.Hour {
+		t.Fatalf("Below low: %d %v", sz, after)
+	}
+	if sz, after := si.Shrink(9, 16); sz != 0 || after != 0 {
+		t.Fatalf("Back above low: %d %v", sz, after)
+	}
+	si.Idle = 0
+	if sz, _ := si.Shrink(3, 16); sz != 4 {
+		t.Fatalf("Shrink to %d", sz)
+	}
+}
+
+func TestShrinkCheck(t *testing.T) {
+	for _, shr := range []ShrinkPolicy{
+		ShrinkFloor{Mode: 5},
+		ShrinkFloor{Floor: -1},
+		&ShrinkIdle{Low: 0},
+		&ShrinkIdle{Low: 0.5, Idle: -1},
+	} {
+		o := DefaultOptions[T]()
+		o.Shrinker = shr
+		if _, err := NewElasticWithOptions(o); err != ErrShrinker {
+			t.Errorf("%+v: err = %v", shr, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+// sized returns an option setter that bounds a channel of byte
+// slices to max bytes.
+func sized(max int, policy Overflow) func(o *Options[[]byte]) {
+	return func(o *Options[[]byte]) {
+		o.Sizer = func(b []byte) int { return len(b) }
+		o.MaxBytes, o.Overflow = max, policy
+	}
+}
+
+func TestSizerDropOldest(t *testing.T) {
+	elc := newUnbuffered(t, sized(1000, DropOldest))
+	for i := 0; i < 20; i++ {
+		elc.S <- make([]byte, 100)
+	}
+	s := elc.Stats()
+	if s.Len != 10 || s.Bytes != 1000 || s.PeakBytes != 1000 ||
+		s.Drops.Oldest != 10 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	elc.S <- make([]byte, 450)
+	if s = elc.Stats(); s.Len != 6 || s.Bytes != 950 || s.Drops.Oldest != 15 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	elc.S <- make([]byte, 2000)
+	if s = elc.Stats(); s.Len != 6 || s.Bytes != 950 ||
+		s.Drops.Oldest != 15 || s.Drops.Newest != 1 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	elc.S <- make([]byte, 10)
+	<-elc.R
+	if s = elc.Stats(); s.Bytes != 860 || s.PeakBytes != 1000 {
+		t.Fatalf("Stats: %+v", s)
+	}
+}
+
+func TestSizerBlock(t *testing.T) {
+	elc := newUnbuffered(t, sized(1000, Block))
+	n := 0
+	for ; n < 100; n++ {
+		select {
+		case elc.S <- make([]byte, 300):
+			continue
+		case <-time.After(10 * time.Millisecond):
+		}
+		break
+	}
+	if s := elc.Stats(); n != 4 || s.Bytes != 1200 || s.Drops.Blocked != 1 {
+		t.Fatalf("Sent %d, stats: %+v", n, s)
+	}
+	<-elc.R
+	elc.S <- make([]byte, 300)
+	if s := elc.Stats(); s.Bytes != 1200 {
+		t.Fatalf("Stats: %+v", s)
+	}
+}
+
+func TestSizerCheck(t *testing.T) {
+	o := DefaultOptions[[]byte]()
+	o.MaxBytes = 10
+	if _, err := NewElasticWithOptions(o); err != ErrSizer {
+		t.Fatalf("err = %v", err)
+	}
+}
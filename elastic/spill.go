@@ -0,0 +1,391 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Codec encodes and decodes the items an elastic channel spills to
+// disk. Encode returns the bytes of a single item; Decode gets back
+// the exact same bytes. Decode must not retain b, which is reused for
+// the next item.
+type Codec[E any] interface {
+	Encode(v E) ([]byte, error)
+	Decode(b []byte) (E, error)
+}
+
+// GobCodec is a Codec that uses encoding/gob. Since every item is
+// encoded separately, it carries the type information with each
+// item; a custom Codec is usually more compact.
+type GobCodec[E any] struct{}
+
+// Encode encodes v using gob.
+func (GobCodec[E]) Encode(v E) ([]byte, error) {
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(&v)
+	return b.Bytes(), err
+}
+
+// Decode decodes b using gob.
+func (GobCodec[E]) Decode(b []byte) (E, error) {
+	var v E
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v)
+	return v, err
+}
+
+// Spill specifies how an elastic channel spills items to disk.
+//
+// Up to Threshold items are kept in the in-memory queue. Beyond that,
+// items are encoded using Codec and appended to segment files, in a
+// private directory created under Dir. Items are read back, in FIFO
+// order, as the consumer catches up. Segment files are deleted once
+// they are drained, and the private directory is removed when the
+// channel is closed.
+type Spill[E any] struct {
+	Dir         string // Parent dir for segment files ("" for os.TempDir).
+	Threshold   int    // Max number of items kept in memory.
+	Codec       Codec[E]
+	SegmentSize int64 // Max segment file size (0 for default).
+}
+
+// Default max segment file size.
+const segmentSize = 64 << 20
+
+// ErrSpill is returned by NewElasticWithOptions if the Spill options
+// are invalid.
+var ErrSpill = errors.New("elastic: invalid spill options")
+
+// check validates the spill options.
+func (sp *Spill[E]) check() error {
+	if sp.Threshold <= 0 || sp.Codec == nil || sp.SegmentSize < 0 {
+		return ErrSpill
+	}
+	return nil
+}
+
+// segment is a spill file.
+type segment struct {
+	name string
+	n    int // Items not yet read back.
+	nw   int // Items written.
+}
+
+// spillQ is a queue that keeps up to sp.Threshold items in memory
+// and spills the rest to disk.
+//
+// Items in memory are always older than items on disk. While there
+// are items on disk, new items are written to disk as well. When
+// the in-memory queue is drained, it is refilled from disk.
+type spillQ[E any] struct {
+	sp   Spill[E]
+	mem  *cQ[E]
+	dir  string        // Private segment directory.
+	segs *cQ[*segment] // Segments, oldest first.
+	seq  int           // Sequence number of next segment.
+	ws   *segment      // Segment being written, or nil.
+	w    *os.File
+	bw   *bufio.Writer
+	wsz  int64 // Bytes written to ws.
+	r    *os.File
+	br   *bufio.Reader
+	buf  []byte
+	disk int   // Items on disk.
+	lost int   // Items lost due to I/O errors.
+	err  error // First I/O or codec error.
+}
+
+// newSpillQ creates and returns a new spill queue. The in-memory
+// queue is created with initial size sz.
+func newSpillQ[E any](sp Spill[E], sz int) (*spillQ[E], error) {
+	if sp.SegmentSize == 0 {
+		sp.SegmentSize = segmentSize
+	}
+	dir, err := os.MkdirTemp(sp.Dir, "elastic-")
+	if err != nil {
+		return nil, err
+	}
+	sq := &spillQ[E]{
+		sp:   sp,
+		mem:  newCQ[E](sz, maxQSz),
+		dir:  dir,
+		segs: newCQ[*segment](1, maxQSz),
+	}
+	return sq, nil
+}
+
+// Len returns the number of elements in the queue (in memory and on
+// disk).
+func (sq *spillQ[E]) Len() int {
+	return sq.mem.Len() + sq.disk
+}
+
+// Cap returns the capacity of the in-memory queue.
+func (sq *spillQ[E]) Cap() int {
+	return sq.mem.Cap()
+}
+
+// PeekFront returns the front element of the queue, without removing
+// it.
+func (sq *spillQ[E]) PeekFront() (el E, ok bool) {
+	return sq.mem.PeekFront()
+}
+
+// PopFront removes the front element from the queue and returns it.
+func (sq *spillQ[E]) PopFront() (el E, ok bool) {
+	el, ok = sq.mem.PopFront()
+	if sq.mem.Empty() && sq.disk > 0 {
+		sq.refill()
+	}
+	return el, ok
+}
+
+// PushBack adds element el to the back of the queue. Returns false
+// if the element could not be encoded, or written to disk.
+func (sq *spillQ[E]) PushBack(el E) (ok bool) {
+	if sq.disk == 0 && sq.mem.Len() < sq.sp.Threshold {
+		return sq.mem.PushBack(el)
+	}
+	b, err := sq.sp.Codec.Encode(el)
+	if err != nil {
+		// Only el is lost (and the caller counts it); the
+		// segment files are intact.
+		if sq.err == nil {
+			sq.err = err
+		}
+		return false
+	}
+	if err := sq.write(b); err != nil {
+		sq.fail(err)
+		return false
+	}
+	return true
+}
+
+// PushFront adds element el to the front of the in-memory queue. The
+// in-memory queue may then exceed the threshold by a few items.
+func (sq *spillQ[E]) PushFront(el E) (ok bool) {
+	return sq.mem.PushFront(el)
+}
+
+// Compact compacts the in-memory queue.
+func (sq *spillQ[E]) Compact(sz int) {
+	sq.mem.Compact(sz)
+}
+
+// Close discards the items on disk and removes the segment
+// directory.
+func (sq *spillQ[E]) Close() error {
+	sq.discard()
+	return os.RemoveAll(sq.dir)
+}
+
+// fail records err, and discards all items on disk.
+func (sq *spillQ[E]) fail(err error) {
+	if sq.err == nil {
+		sq.err = err
+	}
+	sq.lost += sq.disk
+	sq.discard()
+}
+
+// discard closes and removes all segment files.
+func (sq *spillQ[E]) discard() {
+	if sq.r != nil {
+		sq.r.Close()
+		sq.r, sq.br = nil, nil
+	}
+	if sq.w != nil {
+		sq.w.Close()
+		sq.w, sq.bw, sq.ws = nil, nil, nil
+	}
+	for {
+		s, ok := sq.segs.PopFront()
+		if !ok {
+			break
+		}
+		os.Remove(s.name)
+	}
+	sq.disk = 0
+}
+
+// write appends encoded item b to the current segment, starting a
+// new segment if required.
+func (sq *spillQ[E]) write(b []byte) error {
+	if sq.ws == nil || sq.wsz >= sq.sp.SegmentSize {
+		if err := sq.newSegment(); err != nil {
+			return err
+		}
+	}
+	var hdr [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(hdr[:], uint64(len(b)))
+	if _, err := sq.bw.Write(hdr[:n]); err != nil {
+		return err
+	}
+	if _, err := sq.bw.Write(b); err != nil {
+		return err
+	}
+	sq.wsz += int64(n + len(b))
+	sq.ws.n++
+	sq.ws.nw++
+	sq.disk++
+	return nil
+}
+
+// newSegment closes the current segment (if any) for writing, and
+// creates a new one.
+func (sq *spillQ[E]) newSegment() error {
+	if sq.w != nil {
+		if err := sq.bw.Flush(); err != nil {
+			return err
+		}
+		if err := sq.w.Close(); err != nil {
+			return err
+		}
+		sq.w, sq.bw, sq.ws = nil, nil, nil
+	}
+	name := filepath.Join(sq.dir, fmt.Sprintf("seg-%08d", sq.seq))
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	sq.seq++
+	sq.ws = &segment{name: name}
+	sq.segs.PushBack(sq.ws)
+	sq.w, sq.bw, sq.wsz = f, bufio.NewWriter(f), 0
+	return nil
+}
+
+// refill moves items from disk to the in-memory queue, up to the
+// threshold. Items that fail to decode are skipped.
+func (sq *spillQ[E]) refill() {
+	for sq.disk > 0 && sq.mem.Len() < sq.sp.Threshold {
+		el, ok, err := sq.read()
+		if err != nil {
+			sq.fail(err)
+			return
+		}
+		if ok {
+			sq.mem.PushBack(el)
+		}
+	}
+}
+
+// read reads and decodes the next item from the oldest segment. The
+// segment is removed once all its items are read. If the item fails
+// to decode, read counts it as lost and returns ok == false; err is
+// only returned for I/O errors, after which the segment files cannot
+// be trusted.
+func (sq *spillQ[E]) read() (el E, ok bool, err error) {
+	s, _ := sq.segs.PeekFront()
+	if s == sq.ws {
+		if err := sq.bw.Flush(); err != nil {
+			return el, false, err
+		}
+	}
+	if sq.r == nil {
+		if sq.r, err = os.Open(s.name); err != nil {
+			return el, false, err
+		}
+		sq.br = bufio.NewReader(sq.r)
+	}
+	sz, err := binary.ReadUvarint(sq.br)
+	if err != nil {
+		return el, false, err
+	}
+	if uint64(cap(sq.buf)) < sz {
+		sq.buf = make([]byte, sz)
+	}
+	b := sq.buf[:sz]
+	if _, err := io.ReadFull(sq.br, b); err != nil {
+		return el, false, err
+	}
+	s.n--
+	sq.disk--
+	if s.n == 0 {
+		sq.r.Close()
+		sq.r, sq.br = nil, nil
+		if s == sq.ws {
+			sq.w.Close()
+			sq.w, sq.bw, sq.ws = nil, nil, nil
+		}
+		sq.segs.PopFront()
+		os.Remove(s.name)
+	}
+	if el, err = sq.sp.Codec.Decode(b); err != nil {
+		sq.lost++
+		if sq.err == nil {
+			sq.err = err
+		}
+		return el, false, nil
+	}
+	return el, true, nil
+}
+
+// scan calls f with every item in the queue, in order, without
+// removing them. Items on disk are read back through separate file
+// handles, so the queue is not modified. Items that fail to decode
+// are skipped; scan stops at the first I/O error.
+func (sq *spillQ[E]) scan(f func(el E)) {
+	for i := 0; i < sq.mem.Len(); i++ {
+		f(sq.mem.At(i))
+	}
+	if sq.disk == 0 {
+		return
+	}
+	if sq.bw != nil {
+		if err := sq.bw.Flush(); err != nil {
+			return
+		}
+	}
+	var buf []byte
+	for i := 0; i < sq.segs.Len(); i++ {
+		s := sq.segs.At(i)
+		if !sq.scanSegment(s, &buf, f) {
+			return
+		}
+	}
+}
+
+// scanSegment reads the items of segment s that have not been read
+// back yet, using *buf as the read buffer, and calls f with every one
+// that decodes. Returns false on I/O errors.
+func (sq *spillQ[E]) scanSegment(s *segment, buf *[]byte, f func(el E)) bool {
+	r, err := os.Open(s.name)
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+	br := bufio.NewReader(r)
+	for i := 0; i < s.nw; i++ {
+		sz, err := binary.ReadUvarint(br)
+		if err != nil {
+			return false
+		}
+		if uint64(cap(*buf)) < sz {
+			*buf = make([]byte, sz)
+		}
+		b := (*buf)[:sz]
+		if _, err := io.ReadFull(br, b); err != nil {
+			return false
+		}
+		if i < s.nw-s.n {
+			continue
+		}
+		if el, err := sq.sp.Codec.Decode(b); err == nil {
+			f(el)
+		}
+	}
+	return true
+}
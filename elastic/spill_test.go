@@ -0,0 +1,244 @@
+package elastic
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// decCodec encodes T values as decimal strings.
+type decCodec struct{}
+
+func (decCodec) Encode(v T) ([]byte, error) {
+	return strconv.AppendInt(nil, int64(v), 10), nil
+}
+
+func (decCodec) Decode(b []byte) (T, error) {
+	v, err := strconv.Atoi(string(b))
+	return T(v), err
+}
+
+func TestSpill(t *testing.T) {
+	const N, M = 10000, 100
+	dir := t.TempDir()
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.Spill = &Spill[T]{Dir: dir, Threshold: M,
+		Codec: decCodec{}, SegmentSize: 1024}
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	s := elc.Stats()
+	if s.Len != N || s.Spilled != N-M || s.Cap > M*2 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	ds, _ := os.ReadDir(dir)
+	if len(ds) != 1 {
+		t.Fatalf("%d entries in spill dir", len(ds))
+	}
+	segs, _ := os.ReadDir(dir + "/" + ds[0].Name())
+	if len(segs) < 10 {
+		t.Fatalf("%d segment files", len(segs))
+	}
+	// Consume half, then send some more.
+	for i := 0; i < N/2; i++ {
+		if v := <-elc.R; v != T(i) {
+			t.Fatalf("Got %d != %d", v, i)
+		}
+	}
+	segs1, _ := os.ReadDir(dir + "/" + ds[0].Name())
+	if len(segs1) >= len(segs) {
+		t.Fatalf("Drained segments not removed: %d", len(segs1))
+	}
+	for i := N; i < N+M; i++ {
+		elc.S <- T(i)
+	}
+	close(elc.S)
+	i := N / 2
+	for v := range elc.R {
+		if v != T(i) {
+			t.Fatalf("Got %d != %d", v, i)
+		}
+		i++
+	}
+	if i != N+M {
+		t.Fatalf("Received up to %d", i)
+	}
+	if s := elc.Stats(); s.Drops.Failed != 0 || s.SpillErr != nil {
+		t.Fatalf("Stats: %+v", s)
+	}
+	if ds, _ := os.ReadDir(dir); len(ds) != 0 {
+		t.Fatalf("Spill dir not removed")
+	}
+}
+
+// badCodec fails to decode odd values.
+type badCodec struct{ decCodec }
+
+var errOdd = errors.New("odd")
+
+func (badCodec) Decode(b []byte) (T, error) {
+	v, _ := strconv.Atoi(string(b))
+	if v%2 != 0 {
+		return 0, errOdd
+	}
+	return T(v), nil
+}
+
+func TestSpillError(t *testing.T) {
+	const N, M = 100, 10
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.Spill = &Spill[T]{Dir: t.TempDir(), Threshold: M, Codec: badCodec{}}
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	close(elc.S)
+	r := recvAll(t, elc.R)
+	// Items in memory are all delivered; of the items on disk,
+	// only the ones that fail to decode are lost.
+	s := elc.Stats()
+	if len(r) != M+(N-M)/2 || s.Drops.Failed != (N-M)/2 ||
+		s.SpillErr != errOdd {
+		t.Fatalf("Received %d, stats: %+v", len(r), s)
+	}
+	for i, v := range r[M:] {
+		if v != T(M+2*i) {
+			t.Fatalf("Received %d != %d", v, M+2*i)
+		}
+	}
+}
+
+func TestSpillGob(t *testing.T) {
+	const N = 1000
+	type pub struct{ ID int }
+	op := DefaultOptions[pub]()
+	op.Spill = &Spill[pub]{Dir: t.TempDir(), Threshold: 16,
+		Codec: GobCodec[pub]{}}
+	elc, err := NewElasticWithOptions(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		elc.S <- pub{i}
+	}
+	close(elc.S)
+	i := 0
+	for v := range elc.R {
+		if v.ID != i {
+			t.Fatalf("Got %d != %d", v.ID, i)
+		}
+		i++
+	}
+	if i != N {
+		t.Fatalf("Received %d", i)
+	}
+}
+
+var errFail = errors.New("fail")
+
+// encCodec fails to encode value 50.
+type encCodec struct{ decCodec }
+
+func (c encCodec) Encode(v T) ([]byte, error) {
+	if v == 50 {
+		return nil, errFail
+	}
+	return c.decCodec.Encode(v)
+}
+
+func TestSpillEncodeError(t *testing.T) {
+	const N, M = 100, 10
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.Spill = &Spill[T]{Dir: t.TempDir(), Threshold: M, Codec: encCodec{}}
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	// Only the item that fails to encode is lost.
+	if s := elc.Stats(); s.Len != N-1 || s.Drops.Failed != 1 ||
+		s.SpillErr != errFail {
+		t.Fatalf("Stats: %+v", s)
+	}
+	close(elc.S)
+	r := recvAll(t, elc.R)
+	if len(r) != N-1 {
+		t.Fatalf("Received %d", len(r))
+	}
+	for i, v := range r {
+		if exp := T(i + i/50); v != exp {
+			t.Fatalf("Received %d != %d", v, exp)
+		}
+	}
+}
+
+// failCodec fails to encode while fail is set.
+type failCodec struct {
+	decCodec
+	fail *atomic.Bool
+}
+
+func (c failCodec) Encode(v T) ([]byte, error) {
+	if c.fail.Load() {
+		return nil, errFail
+	}
+	return c.decCodec.Encode(v)
+}
+
+func TestSpillSnapshot(t *testing.T) {
+	const N, M, K = 100, 10, 15
+	var fail atomic.Bool
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.Spill = &Spill[T]{Dir: t.TempDir(), Threshold: M,
+		Codec: failCodec{fail: &fail}, SegmentSize: 64}
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	// Leave the oldest segment partially read.
+	for i := 0; i < K; i++ {
+		<-elc.R
+	}
+	fail.Store(true)
+	vs := elc.Snapshot()
+	if len(vs) != N-K {
+		t.Fatalf("Snapshot of %d items", len(vs))
+	}
+	for i, v := range vs {
+		if v != T(K+i) {
+			t.Fatalf("Snapshot[%d] = %d", i, v)
+		}
+	}
+	if s := elc.Stats(); s.Len != N-K || s.Drops.Failed != 0 ||
+		s.SpillErr != nil {
+		t.Fatalf("Stats: %+v", s)
+	}
+	close(elc.S)
+	r := recvAll(t, elc.R)
+	if len(r) != N-K {
+		t.Fatalf("Received %d", len(r))
+	}
+	for i, v := range r {
+		if v != T(K+i) {
+			t.Fatalf("Received %d != %d", v, K+i)
+		}
+	}
+}
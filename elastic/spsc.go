@@ -0,0 +1,185 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import "sync/atomic"
+
+// SPSC is an elastic queue of E-typed elements for exactly one
+// sender and one receiver. It does not use a goroutine, or locks:
+// items are kept in a chain of lock-free rings with atomic head and
+// tail indexes. Send never blocks; Recv parks the receiving
+// goroutine while the queue is empty.
+//
+// Send and Close must only be called by the sender goroutine, and
+// Recv and TryRecv only by the receiver goroutine. An SPSC must be
+// created with NewSPSC, and must not be copied after first use.
+type SPSC[E any] struct {
+	// Sender side.
+	w  *spscRing[E] // Ring being written.
+	in atomic.Uint64
+	_  [48]byte // Keep sides on separate cache lines.
+
+	// Receiver side.
+	r   *spscRing[E] // Ring being read.
+	out atomic.Uint64
+	_   [48]byte
+
+	mode   ShrinkMode
+	shr    atomic.Bool // Receiver asks the sender to shrink.
+	park   atomic.Bool // Receiver is (about to be) parked.
+	closed atomic.Bool
+	wake   chan struct{} // Wakes up the parked receiver.
+}
+
+// spscRing is a ring of the SPSC queue. Like cQ, it uses
+// free-running indexes, and a power-of-2 buffer size. Only the sender
+// writes tail and the buffer slot at tail, and only the receiver
+// writes head. Once the sender sets next, it never writes to the
+// ring again; the receiver moves to next after draining the ring.
+//
+// The growth protocol is: when the ring is full, the sender links a
+// new ring of double size and continues there. Similarly, when the
+// receiver asks it to shrink, the sender links a smaller ring.
+type spscRing[E any] struct {
+	b    []E
+	m    uint32 // Index mask.
+	head atomic.Uint32
+	tail atomic.Uint32
+	next atomic.Pointer[spscRing[E]]
+}
+
+// Initial (and minimum) ring size.
+const spscInit = 32
+
+// newSPSCRing creates and returns a new ring of size sz (must be a
+// power of 2).
+func newSPSCRing[E any](sz uint32) *spscRing[E] {
+	return &spscRing[E]{b: make([]E, sz), m: sz - 1}
+}
+
+// NewSPSC creates and returns a new, empty, SPSC queue, using the
+// specified shrink mode.
+func NewSPSC[E any](mode ShrinkMode) *SPSC[E] {
+	r := newSPSCRing[E](spscInit)
+	return &SPSC[E]{
+		w:    r,
+		r:    r,
+		mode: mode,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Len returns the number of items in the queue.
+func (q *SPSC[E]) Len() int {
+	out := q.out.Load()
+	return int(q.in.Load() - out)
+}
+
+// Send adds v to the back of the queue. Send panics if the queue has
+// been closed.
+func (q *SPSC[E]) Send(v E) {
+	if q.closed.Load() {
+		panic("Send on closed queue")
+	}
+	w := q.w
+	l := w.tail.Load() - w.head.Load()
+	if sz := uint32(len(w.b)); l == sz {
+		q.link(min(sz<<1, maxQSz))
+	} else if q.shr.Load() {
+		q.shr.Store(false)
+		if nsz := max(roundUp2(l+1), spscInit); nsz < sz {
+			q.link(nsz)
+		}
+	}
+	w = q.w
+	t := w.tail.Load()
+	w.b[t&w.m] = v
+	w.tail.Store(t + 1)
+	q.in.Add(1)
+	q.unpark()
+}
+
+// link starts a new ring of size sz, for the sender to write to.
+func (q *SPSC[E]) link(sz uint32) {
+	nw := newSPSCRing[E](sz)
+	q.w.next.Store(nw)
+	q.w = nw
+}
+
+// Close closes the queue. Items already in the queue can still be
+// received.
+func (q *SPSC[E]) Close() {
+	q.closed.Store(true)
+	q.unpark()
+}
+
+// unpark wakes up the receiver, if parked.
+func (q *SPSC[E]) unpark() {
+	if q.park.Load() && q.park.CompareAndSwap(true, false) {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// TryRecv removes and returns the item at the front of the queue,
+// without blocking. Returns false if the queue is empty.
+func (q *SPSC[E]) TryRecv() (E, bool) {
+	var zero E
+	r := q.r
+	for {
+		// Load next before tail: if next is set, the tail
+		// loaded after it is final.
+		nx := r.next.Load()
+		h, t := r.head.Load(), r.tail.Load()
+		if h != t {
+			i := h & r.m
+			v := r.b[i]
+			r.b[i] = zero
+			r.head.Store(h + 1)
+			q.out.Add(1)
+			q.shrink(r, t-h-1)
+			return v, true
+		}
+		if nx == nil {
+			return zero, false
+		}
+		r = nx
+		q.r = r
+	}
+}
+
+// shrink asks the sender to shrink, if required by the shrink mode.
+// l is the number of items left in ring r.
+func (q *SPSC[E]) shrink(r *spscRing[E], l uint32) {
+	sz := len(r.b)
+	if sz > spscInit && r.next.Load() == nil &&
+		mustShrink(q.mode, int(l), sz) && !q.shr.Load() {
+		q.shr.Store(true)
+	}
+}
+
+// Recv removes and returns the item at the front of the queue,
+// parking the calling goroutine while the queue is empty. Returns
+// false if the queue is closed and empty.
+func (q *SPSC[E]) Recv() (E, bool) {
+	for {
+		if v, ok := q.TryRecv(); ok {
+			return v, true
+		}
+		if q.closed.Load() {
+			// Items sent before Close are visible now.
+			return q.TryRecv()
+		}
+		q.park.Store(true)
+		if q.Len() > 0 || q.closed.Load() {
+			q.park.Store(false)
+			continue
+		}
+		<-q.wake
+	}
+}
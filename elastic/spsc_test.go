@@ -0,0 +1,63 @@
+package elastic
+
+import "testing"
+
+func TestSPSC(t *testing.T) {
+	q := NewSPSC[T](Shrink)
+	if _, ok := q.TryRecv(); ok {
+		t.Fatal("TryRecv on empty queue")
+	}
+	for i := 0; i < 1000; i++ {
+		q.Send(T(i))
+	}
+	if q.Len() != 1000 {
+		t.Fatalf("Len %d", q.Len())
+	}
+	for i := 0; i < 1000; i++ {
+		if v, ok := q.TryRecv(); !ok || v != T(i) {
+			t.Fatalf("TryRecv %d, %v", v, ok)
+		}
+	}
+	q.Send(1000)
+	if sz := len(q.w.b); sz != spscInit {
+		t.Fatalf("Ring size %d after shrink", sz)
+	}
+	q.Close()
+	if v, ok := q.Recv(); !ok || v != 1000 {
+		t.Fatalf("Recv %d, %v", v, ok)
+	}
+	if _, ok := q.Recv(); ok {
+		t.Fatal("Recv on closed queue")
+	}
+}
+
+func testSPSCData(t *testing.T, mode ShrinkMode) {
+	const N = 100000
+	q := NewSPSC[T](mode)
+	go func() {
+		for i := 0; i < N; i++ {
+			q.Send(T(i))
+		}
+		q.Close()
+	}()
+	var i int
+	for {
+		v, ok := q.Recv()
+		if !ok {
+			break
+		}
+		if v != T(i) {
+			t.Fatalf("Received %d != %d", v, i)
+		}
+		i++
+	}
+	if i != N {
+		t.Fatalf("Received %d items", i)
+	}
+}
+
+func TestSPSCData(t *testing.T) {
+	for _, mode := range []ShrinkMode{Shrink, ShrinkEmpty, NoShrink} {
+		testSPSCData(t, mode)
+	}
+}
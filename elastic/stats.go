@@ -0,0 +1,46 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+// Stats is a snapshot of the state and the counters of an elastic
+// channel.
+type Stats struct {
+	Len       int    // Items in the internal queue.
+	Cap       int    // Size of the queue buffer (element slots).
+	MaxLen    int    // High-water mark of Len.
+	Bytes     int    // Bytes in the internal queue (needs Sizer).
+	PeakBytes int    // High-water mark of Bytes.
+	SendLen   int    // Items in the send-side buffer.
+	RecvLen   int    // Items in the receive-side buffer.
+	In        uint64 // Items received from the send side.
+	Out       uint64 // Items delivered to the receive side.
+	Grows     uint64 // Times the queue buffer was grown.
+	Compacts  uint64 // Times the queue buffer was shrinked.
+	Drops     Drops  // Overflow counters.
+	High      bool   // Above the high watermark (see Marks).
+	Paused    bool   // Delivery is paused (see Pause).
+	Spilled   int    // Items spilled to disk (included in Len).
+	SpillErr  error  // First spill I/O or codec error.
+}
+
+// Stats returns a snapshot of the elastic channel's state and
+// counters. It is safe to call while the channel is in use, and
+// after it has been closed.
+func (e Elastic[E]) Stats() Stats {
+	var s Stats
+	en := e.eng
+	en.view(func() {
+		s = en.st
+		s.Len, s.Cap = en.q.Len(), en.q.Cap()
+		s.Bytes = en.bytes
+		s.SendLen, s.RecvLen = len(en.cin), len(en.cout)
+		if sq := en.sq; sq != nil {
+			s.Spilled, s.SpillErr = sq.disk, sq.err
+			s.Drops.Failed += uint64(sq.lost)
+		}
+	})
+	return s
+}
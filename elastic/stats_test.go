@@ -0,0 +1,52 @@
+package elastic
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	const N = 1000
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	elc, _ := NewElasticWithOptions(o)
+	for i := 0; i < N; i++ {
+		elc.S <- T(i)
+	}
+	s := elc.Stats()
+	if s.Len != N || s.Cap != 1024 || s.MaxLen != N ||
+		s.In != N || s.Out != 0 || s.Grows != 10 || s.Compacts != 0 {
+		t.Fatalf("Stats after send: %+v", s)
+	}
+	for i := 0; i < N-10; i++ {
+		<-elc.R
+	}
+	s = elc.Stats()
+	if s.Len != 10 || s.Cap != 16 || s.MaxLen != N ||
+		s.In != N || s.Out != N-10 || s.Compacts != 6 {
+		t.Fatalf("Stats after receive: %+v", s)
+	}
+	close(elc.S)
+	for range elc.R {
+	}
+	s = elc.Stats()
+	if s.Len != 0 || s.Out != N {
+		t.Fatalf("Stats after close: %+v", s)
+	}
+}
+
+func TestStatsConcurrent(t *testing.T) {
+	const N = 8192
+	elc := NewElasticT()
+	endP := make(chan int)
+	endC := make(chan int)
+	go produce(N, elc.S, endP)
+	go consume(N, elc.R, endC)
+	for i := 0; i < 100; i++ {
+		if s := elc.Stats(); s.Out > s.In {
+			t.Fatalf("Out > In: %+v", s)
+		}
+	}
+	<-endP
+	<-endC
+	if s := elc.Stats(); s.In != N || s.Out != N {
+		t.Fatalf("Stats: %+v", s)
+	}
+}
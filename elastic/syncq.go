@@ -0,0 +1,194 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// SyncQueue is an elastic queue of E-typed elements that does not use
+// a goroutine. Items are sent and received by calling methods, which
+// access the queue under a mutex, instead of going through two
+// channels and the elastic channel goroutine. Use Chan to get a
+// receive channel, when a select statement is required.
+//
+// A SyncQueue must be created with NewSyncQueue, and must not be
+// copied after first use.
+type SyncQueue[E any] struct {
+	mu     sync.Mutex
+	q      *cQ[E]
+	mode   ShrinkMode
+	closed bool
+	ne     chan struct{} // Closed when items are added, or nil.
+	nf     chan struct{} // Closed when the queue is not full, or nil.
+}
+
+// ErrClosed is returned by SyncQueue.Recv when the queue has been
+// closed and drained.
+var ErrClosed = errors.New("elastic: queue closed")
+
+// NewSyncQueue creates and returns a new, empty, sync queue, using
+// the specified shrink mode.
+func NewSyncQueue[E any](mode ShrinkMode) *SyncQueue[E] {
+	return &SyncQueue[E]{q: newCQ[E](1, maxQSz), mode: mode}
+}
+
+// Send adds v to the back of the queue. It blocks only if the queue
+// has reached its maximum size. Send panics if the queue has been
+// closed.
+func (sq *SyncQueue[E]) Send(v E) {
+	for {
+		sq.mu.Lock()
+		if sq.closed {
+			sq.mu.Unlock()
+			panic("Send on closed queue")
+		}
+		if sq.push(v) {
+			sq.mu.Unlock()
+			return
+		}
+		if sq.nf == nil {
+			sq.nf = make(chan struct{})
+		}
+		nf := sq.nf
+		sq.mu.Unlock()
+		<-nf
+	}
+}
+
+// TrySend adds v to the back of the queue, without blocking. Returns
+// false if the queue is full, or closed.
+func (sq *SyncQueue[E]) TrySend(v E) bool {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return !sq.closed && sq.push(v)
+}
+
+// Recv removes and returns the item at the front of the queue,
+// waiting for one if the queue is empty. It returns ErrClosed if the
+// queue is closed and empty, or ctx.Err() if ctx is done first.
+func (sq *SyncQueue[E]) Recv(ctx context.Context) (E, error) {
+	for {
+		sq.mu.Lock()
+		if v, ok := sq.pop(); ok {
+			sq.mu.Unlock()
+			return v, nil
+		}
+		if sq.closed {
+			sq.mu.Unlock()
+			var v E
+			return v, ErrClosed
+		}
+		if sq.ne == nil {
+			sq.ne = make(chan struct{})
+		}
+		ne := sq.ne
+		sq.mu.Unlock()
+		select {
+		case <-ne:
+		case <-ctx.Done():
+			var v E
+			return v, ctx.Err()
+		}
+	}
+}
+
+// TryRecv removes and returns the item at the front of the queue,
+// without blocking. Returns false if the queue is empty.
+func (sq *SyncQueue[E]) TryRecv() (E, bool) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.pop()
+}
+
+// Close closes the queue. Items already in the queue can still be
+// received; once they are, Recv returns ErrClosed. Close panics if
+// the queue is already closed.
+func (sq *SyncQueue[E]) Close() {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if sq.closed {
+		panic("Close of closed queue")
+	}
+	sq.closed = true
+	sq.wake(&sq.ne)
+}
+
+// Len returns the number of items in the queue.
+func (sq *SyncQueue[E]) Len() int {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.q.Len()
+}
+
+// Chan returns a channel that receives the items of the queue. It
+// starts a goroutine that moves items from the queue to the channel,
+// one at a time. The channel is closed once the queue is closed and
+// drained, or when ctx is done; an item taken from the queue, but
+// not yet received from the channel, is then put back to the front
+// of the queue.
+func (sq *SyncQueue[E]) Chan(ctx context.Context) <-chan E {
+	c := make(chan E)
+	go func() {
+		defer close(c)
+		for {
+			v, err := sq.Recv(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case c <- v:
+			case <-ctx.Done():
+				sq.unrecv(v)
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// wake closes the wait channel *c, if any, waking up its waiters.
+func (sq *SyncQueue[E]) wake(c *chan struct{}) {
+	if *c != nil {
+		close(*c)
+		*c = nil
+	}
+}
+
+// push adds v to the back of the queue. Returns false if the queue is
+// full.
+func (sq *SyncQueue[E]) push(v E) bool {
+	if !sq.q.PushBack(v) {
+		return false
+	}
+	sq.wake(&sq.ne)
+	return true
+}
+
+// pop removes the front item from the queue, and compacts the queue
+// if required by the shrink mode.
+func (sq *SyncQueue[E]) pop() (E, bool) {
+	v, ok := sq.q.PopFront()
+	if !ok {
+		return v, false
+	}
+	sq.wake(&sq.nf)
+	if mustShrink(sq.mode, sq.q.Len(), sq.q.Cap()) {
+		sq.q.Compact(1)
+	}
+	return v, true
+}
+
+// unrecv puts v back to the front of the queue.
+func (sq *SyncQueue[E]) unrecv(v E) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if sq.q.PushFront(v) {
+		sq.wake(&sq.ne)
+	}
+}
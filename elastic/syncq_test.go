@@ -0,0 +1,151 @@
+package elastic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSyncQueue(t *testing.T) {
+	sq := NewSyncQueue[T](Shrink)
+	if _, ok := sq.TryRecv(); ok {
+		t.Fatal("TryRecv on empty queue")
+	}
+	for i := 0; i < 100; i++ {
+		sq.Send(T(i))
+	}
+	if !sq.TrySend(100) || sq.Len() != 101 {
+		t.Fatalf("Len %d", sq.Len())
+	}
+	for i := 0; i < 50; i++ {
+		if v, ok := sq.TryRecv(); !ok || v != T(i) {
+			t.Fatalf("TryRecv %d, %v", v, ok)
+		}
+	}
+	sq.Close()
+	if sq.TrySend(101) {
+		t.Fatal("TrySend on closed queue")
+	}
+	ctx := context.Background()
+	for i := 50; i <= 100; i++ {
+		if v, err := sq.Recv(ctx); err != nil || v != T(i) {
+			t.Fatalf("Recv %d, %v", v, err)
+		}
+	}
+	if _, err := sq.Recv(ctx); err != ErrClosed {
+		t.Fatalf("Recv on closed queue: %v", err)
+	}
+	if c := sq.q.Cap(); c != 1 {
+		t.Fatalf("Cap %d", c)
+	}
+}
+
+func TestSyncQueueRecvCtx(t *testing.T) {
+	sq := NewSyncQueue[T](Shrink)
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if _, err := sq.Recv(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Recv: %v", err)
+	}
+	go func() {
+		time.Sleep(1 * time.Millisecond)
+		sq.Send(1)
+	}()
+	if v, err := sq.Recv(context.Background()); err != nil || v != 1 {
+		t.Fatalf("Recv %d, %v", v, err)
+	}
+}
+
+func TestSyncQueueChan(t *testing.T) {
+	const N = 8192
+	sq := NewSyncQueue[T](Shrink)
+	ctx, cancel := context.WithCancel(context.Background())
+	c := sq.Chan(ctx)
+	sq.Send(0)
+	sq.Send(1)
+	if v := <-c; v != 0 {
+		t.Fatalf("Received %d", v)
+	}
+	cancel()
+	for range c {
+	}
+	if v, ok := sq.TryRecv(); !ok || v != 1 {
+		t.Fatalf("TryRecv %d, %v", v, ok)
+	}
+
+	end := make(chan int)
+	go func() {
+		for i := 0; i < N; i++ {
+			sq.Send(T(i))
+		}
+		sq.Close()
+		end <- N
+	}()
+	go consume(N, sq.Chan(context.Background()), end)
+	if r := <-end; r != N {
+		t.Fatalf("ret %d != %d", r, N)
+	}
+	if r := <-end; r != N {
+		t.Fatalf("ret %d != %d", r, N)
+	}
+}
+
+func benchSyncSeq(b *testing.B, mode ShrinkMode) {
+	sq := NewSyncQueue[T](mode)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sq.Send(T(i))
+	}
+	for i := 0; i < b.N; i++ {
+		if v, _ := sq.Recv(ctx); v != T(i) {
+			b.Fatalf("Received %d != %d", v, i)
+		}
+	}
+	b.StopTimer()
+}
+
+func BenchmarkSyncSeqShrink(b *testing.B) {
+	benchSyncSeq(b, Shrink)
+}
+
+func BenchmarkSyncSeqShrinkEmpty(b *testing.B) {
+	benchSyncSeq(b, ShrinkEmpty)
+}
+
+func BenchmarkSyncSeqNoShrink(b *testing.B) {
+	benchSyncSeq(b, NoShrink)
+}
+
+func benchSyncCon(b *testing.B, mode ShrinkMode) {
+	sq := NewSyncQueue[T](mode)
+	ctx := context.Background()
+	end := make(chan int)
+	b.ResetTimer()
+	go func() {
+		for i := 0; i < b.N; i++ {
+			sq.Send(T(i))
+		}
+		end <- b.N
+	}()
+	for i := 0; i < b.N; i++ {
+		if v, _ := sq.Recv(ctx); v != T(i) {
+			b.Fatalf("Received %d != %d", v, i)
+		}
+	}
+	<-end
+	b.StopTimer()
+}
+
+func BenchmarkSyncConShrink(b *testing.B) {
+	benchSyncCon(b, Shrink)
+}
+
+func BenchmarkSyncConShrinkEmpty(b *testing.B) {
+	benchSyncCon(b, ShrinkEmpty)
+}
+
+func BenchmarkSyncConNoShrink(b *testing.B) {
+	benchSyncCon(b, NoShrink)
+}
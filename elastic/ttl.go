@@ -0,0 +1,112 @@
+// Copyright (c) 2014, Nick Patavalis (npat@efault.net).
+// All rights reserved.
+// Use of this source code is governed by a BSD-style license that can
+// be found in the LICENSE file.
+
+package elastic
+
+import "time"
+
+// ttlQ is a queue that keeps the enqueue time of every element, next
+// to the element. It wraps another queue, and keeps the timestamps in
+// a parallel circular queue, which is pushed and popped in lockstep
+// with the wrapped one.
+type ttlQ[E any] struct {
+	queue[E]
+	ts   *cQ[int64] // Enqueue times, as offsets from base.
+	base time.Time
+}
+
+// newTTLQ returns a new ttlQ wrapping q, which must be empty.
+func newTTLQ[E any](q queue[E]) *ttlQ[E] {
+	return &ttlQ[E]{
+		queue: q,
+		ts:    newCQ[int64](1, maxQSz),
+		base:  time.Now(),
+	}
+}
+
+// now returns the current time as an offset from tq.base.
+func (tq *ttlQ[E]) now() int64 {
+	return int64(time.Since(tq.base))
+}
+
+// PopFront removes the front element and its timestamp from the
+// queue, and returns the element.
+func (tq *ttlQ[E]) PopFront() (el E, ok bool) {
+	if el, ok = tq.queue.PopFront(); ok {
+		tq.ts.PopFront()
+	}
+	return el, ok
+}
+
+// PushBack adds element el to the back of the queue, timestamped with
+// the current time.
+func (tq *ttlQ[E]) PushBack(el E) (ok bool) {
+	if ok = tq.queue.PushBack(el); ok {
+		tq.ts.PushBack(tq.now())
+	}
+	return ok
+}
+
+// PushFront adds element el to the front of the queue, timestamped
+// with the current time. Items behind it, queued earlier, expire
+// once they reach the front.
+func (tq *ttlQ[E]) PushFront(el E) (ok bool) {
+	if ok = tq.queue.PushFront(el); ok {
+		tq.ts.PushFront(tq.now())
+	}
+	return ok
+}
+
+// pushBackAt adds element el to the back of the queue, timestamped
+// with t.
+func (tq *ttlQ[E]) pushBackAt(el E, t int64) (ok bool) {
+	if ok = tq.queue.PushBack(el); ok {
+		tq.ts.PushBack(t)
+	}
+	return ok
+}
+
+// Compact compacts the wrapped queue, and the timestamp queue.
+func (tq *ttlQ[E]) Compact(sz int) {
+	tq.queue.Compact(sz)
+	tq.ts.Compact(1)
+}
+
+// front returns the enqueue time of the front element.
+func (tq *ttlQ[E]) front() (t int64, ok bool) {
+	return tq.ts.PeekFront()
+}
+
+// expire drops the items at the front of the queue that have been
+// queued for longer than the TTL, and arms the expiry timer for the
+// new front item. It must only be called if TTL is set (en.tq !=
+// nil).
+func (en *engine[E]) expire() {
+	ttl := int64(en.o.TTL)
+	now := en.tq.now()
+	for {
+		t, ok := en.tq.front()
+		if !ok {
+			return
+		}
+		if dl := t + ttl; dl > now {
+			if dl != en.edl {
+				en.edl = dl
+				if en.etmr == nil {
+					en.etmr = time.NewTimer(time.Duration(dl - now))
+				} else {
+					en.etmr.Reset(time.Duration(dl - now))
+				}
+				en.ec = en.etmr.C
+			}
+			return
+		}
+		v := en.pop()
+		en.st.Drops.Expired++
+		if en.o.OnExpire != nil {
+			en.o.OnExpire(v)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package elastic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTL(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+	var expired []T
+	o := DefaultOptions[T]()
+	o.SendBuffer, o.ReceiveBuffer = 0, 0
+	o.TTL = ttl
+	o.OnExpire = func(v T) { expired = append(expired, v) }
+	elc, err := NewElasticWithOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		elc.S <- T(i)
+	}
+	time.Sleep(2 * ttl)
+	// Expired by the timer, without any receive.
+	if s := elc.Stats(); s.Len != 0 || s.Drops.Expired != 10 {
+		t.Fatalf("Stats: %+v", s)
+	}
+	for i := 10; i < 15; i++ {
+		elc.S <- T(i)
+	}
+	close(elc.S)
+	r := recvAll(t, elc.R)
+	if len(r) != 5 || r[0] != 10 {
+		t.Fatalf("Received %v", r)
+	}
+	if len(expired) != 10 || expired[9] != 9 {
+		t.Fatalf("Expired %v", expired)
+	}
+}
+
+func TestTTLData(t *testing.T) {
+	const N = 8192
+	o := DefaultOptions[T]()
+	o.TTL = time.Hour
+	elc, _ := NewElasticWithOptions(o)
+	endP := make(chan int)
+	endC := make(chan int)
+	go produce(N, elc.S, endP)
+	go consume(N, elc.R, endC)
+	if r := <-endP; r != N {
+		t.Fatalf("Producer ret %d != %d", r, N)
+	}
+	if r := <-endC; r != N {
+		t.Fatalf("Consumer ret %d != %d", r, N)
+	}
+	if s := elc.Stats(); s.Drops.Expired != 0 {
+		t.Fatalf("Stats: %+v", s)
+	}
+}
+
+func TestTTLSpill(t *testing.T) {
+	o := DefaultOptions[T]()
+	o.TTL = 50 * time.Millisecond
+	o.Spill = &Spill[T]{Dir: t.TempDir(), Threshold: 2, Codec: decCodec{}}
+	if _, err := NewElasticWithOptions(o); err != ErrTTL {
+		t.Fatalf("err = %v", err)
+	}
+}